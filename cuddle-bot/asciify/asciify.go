@@ -5,81 +5,214 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"io"
+	"math"
 	"os"
-	"slices"
-	"strings"
+	"unicode/utf8"
 
-	// import for initialization side-effects
-	_ "image/jpeg"
-	_ "image/png"
+	"golang.org/x/image/font"
 )
 
-const (
-	gradient      = "$@B%8&WM#*oahkbdpqwmZO0QLCJUYXzcvunxrjft/\\|()1{}[]?-_+~<>i!lI;:,\"^`'. "
-	gradientRatio = float32(len(gradient)) / 256
-)
-
-var (
-	extensions = []string{".png", ".jpg", ".jpeg"}
-)
+// Options configures how an image is converted to text.
+type Options struct {
+	// MaxWidth and MaxHeight bound the output size in "character width" and "character height" units respectively.
+	MaxWidth  int
+	MaxHeight int
+	// Ramp lists the characters to draw from darkest to lightest (on a light background), and may contain multi-byte runes.
+	// DefaultRamp is used when it's empty.
+	Ramp string
+	// Invert flips the ramp, producing a negative of the image.
+	Invert bool
+	// Background is the color the text will be displayed on, which decides which end of the ramp is drawn for dark pixels.
+	Background Background
+	// Resample selects how source pixels are combined into each character, defaulting to nearest-pixel sampling.
+	Resample Resample
+	// Dither selects how quantization error is spread between cells when picking characters, defaulting to none.
+	Dither Dither
+	// Mode selects the renderer, defaulting to a character ramp.
+	Mode Mode
+	// Threshold is the luminance in (0, 1) at or above which a pixel counts as light in ModeBraille, or the edge strength in
+	// (0, 1) relative to the strongest edge at or above which a cell holds an edge in ModeEdges. Anything else, including the
+	// zero value, picks a threshold based on the image's statistics.
+	Threshold float32
+	// Blend fills the cells between edges from the ramp in ModeEdges, instead of leaving them blank.
+	Blend bool
+	// Color selects whether each cell is colored with ANSI escape sequences, and which palette they use.
+	Color ColorMode
+	// MaxChars, if positive, shrinks the output until the encoded text (escape sequences included) has no more than this many
+	// characters, e.g. to fit in a 2000 character Discord message.
+	MaxChars int
+	// Face is the font Rasterize draws with and ModeGlyph matches against, defaulting to Go Mono.
+	Face font.Face
+	// CellAspect is the width of a character cell divided by its height, DefaultCellAspect if it isn't positive, which keeps
+	// the image from looking squashed or stretched.
+	CellAspect float64
+	// Fit selects how the image is sized to the MaxWidth x MaxHeight box, defaulting to fitting entirely inside it.
+	Fit Fit
+	// Matte is the color that transparent and translucent pixels are blended over. If it's nil, it defaults to black on a dark
+	// Background and white on a light one, so transparent areas blend in with whatever the text is displayed on.
+	Matte color.Color
+	// MaxPixels is the largest image, in total pixels, that will be decoded from a stream, DefaultMaxPixels if it isn't
+	// positive. It doesn't apply to images that are already decoded.
+	MaxPixels int
+	// Tone adjusts the image's brightness, contrast and so on before characters are picked.
+	Tone Tone
+	// Workers, if more than 1, splits resampling (and glyph matching in ModeGlyph) by rows across this many goroutines, e.g.
+	// runtime.GOMAXPROCS(0). The output is the same either way.
+	Workers int
+	// Crop, if not empty, is the part of the image to draw, relative to the top left corner of the image. It's applied before
+	// fitting the image to the box.
+	Crop image.Rectangle
+}
 
-// Asciify converts an image to grayscale, then picks pixels at regular intervals to convert to a text character roughly
-// corresponding to how dark the pixel is, and builds a multiline string from all those characters in roughly the same aspect
-// ratio. The maxWidth and maxHeight parameters are in "character width" and "character height" units respectively, so the
-// output height of a square image will be output width / 2.
+// Asciify opens an image from disk and converts it with AsciifyImage. The maxWidth and maxHeight parameters are in "character
+// width" and "character height" units respectively, so the output height of a square image will be output width / 2.
 func Asciify(filename string, maxWidth int, maxHeight int) (string, error) {
-	// check some inputs
-	extension := strings.ToLower(filename[strings.LastIndex(filename, "."):])
-	if !slices.Contains(extensions, extension) {
-		return "", fmt.Errorf("file (%s) must be of type png (.png) or jpeg (.jpg/.jpeg)", filename)
-	}
-	if maxWidth == 0 || maxHeight == 0 {
-		return "", errors.New("ascii max size must be wider/taller than 0")
-	}
-
-	// open up the image from disk
+	// open up the image from disk, its extension doesn't matter since the format is sniffed from its content
 	reader, err := os.Open(filename)
 	if err != nil {
 		return "", err
 	}
 	defer reader.Close()
-	m, _, err := image.Decode(reader)
+	return AsciifyReader(reader, Options{MaxWidth: maxWidth, MaxHeight: maxHeight})
+}
+
+// AsciifyReader decodes an image in any of the supported Formats from a stream, e.g. an HTTP response body, and converts it
+// with AsciifyImage. Animated GIFs only have their first frame converted, see AsciifyGIF for the rest.
+func AsciifyReader(r io.Reader, opts Options) (string, error) {
+	m, err := Decode(r, opts)
 	if err != nil {
 		return "", err
 	}
+	return AsciifyImage(m, opts)
+}
 
-	// figure out how wide and tall the text output will actually be
-	max := m.Bounds().Max
-	inWidth, inHeight := float32(max.X), float32(max.Y)
-	if inWidth < 1 || inHeight < 1 {
-		return "", errors.New("input image size must be wider/taller than 0")
+// Decode decodes an image in any of the supported Formats from a stream. Jpegs are rotated and/or flipped upright according
+// to their EXIF orientation, since phones usually save photos sideways and leave it to the viewer to fix. Animated GIFs only
+// have their first frame decoded. Images with more than opts.MaxPixels pixels are rejected with ErrTooLarge before they're
+// decoded.
+func Decode(r io.Reader, opts Options) (image.Image, error) {
+	format, config, r, err := Sniff(r)
+	if err != nil {
+		return nil, err
 	}
-	outWidth, outHeight := float32(maxWidth), float32(maxHeight)
+	if err := checkSize(config, opts); err != nil {
+		return nil, err
+	}
+	header := &prefixWriter{limit: exifHeaderLimit}
+	m, _, err := image.Decode(io.TeeReader(r, header))
+	if err != nil {
+		return nil, err
+	}
+	if format == "jpeg" {
+		m = orient(m, jpegOrientation(header.buf))
+	}
+	return m, nil
+}
 
-	// adjust output size to match the aspect ratio of the input image
-	if inWidth > inHeight {
-		outHeight = outWidth / inWidth * inHeight
-	} else if inHeight > inWidth {
-		outWidth = outHeight / inHeight * inWidth
+// AsciifyImage converts an image to grayscale, then resamples it to one value per output cell to convert to a text character
+// roughly corresponding to how dark the cell is, and builds a multiline string from all those characters in roughly the same
+// aspect ratio.
+func AsciifyImage(m image.Image, opts Options) (string, error) {
+	g, err := Render(m, opts)
+	if err != nil {
+		return "", err
 	}
+	return g.Text(opts.colorMode()), nil
+}
 
-	// make sure rounding didn't wreck us somehow
-	if outWidth == 0 || outHeight == 0 {
-		return "", errors.New("ascii output size must be wider/taller than 0")
+// Render converts an image to a grid of cells the same way AsciifyImage does, for writers that need more than plain text.
+func Render(m image.Image, opts Options) (*Grid, error) {
+	if opts.MaxWidth < 1 || opts.MaxHeight < 1 {
+		return nil, errors.New("ascii max size must be wider/taller than 0")
+	}
+	r, err := newRamp(opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// scan the pixels, convert to grayscale, append corresponding characters to the output string
-	var sb strings.Builder
-	xMax, yMax := int(outWidth), int(outHeight)
-	var yf float32
-	for y := 0; y < yMax; y++ {
-		yf = float32(y)
-		for x := 0; x < xMax; x++ {
-			gray := color.GrayModel.Convert(m.At(int(float32(x)/outWidth*inWidth), int(yf/outHeight*inHeight))).(color.Gray)
-			sb.WriteByte(gradient[uint8(float32(gray.Y)*gradientRatio)])
+	// figure out which part of the image to draw, and how wide and tall the text output will actually be
+	src, w, h, err := layout(m.Bounds(), opts)
+	if err != nil {
+		return nil, err
+	}
+	m = crop(m, src)
+
+	for {
+		g, err := renderGrid(m, w, h, r, opts)
+		if err != nil {
+			return nil, err
+		}
+		if opts.MaxChars <= 0 {
+			return g, nil
+		}
+
+		n := utf8.RuneCountInString(g.Text(opts.colorMode()))
+		if n <= opts.MaxChars {
+			return g, nil
+		}
+		if w, h, err = shrink(w, h, n, opts.MaxChars); err != nil {
+			return nil, err
 		}
-		sb.WriteString("\n")
 	}
+}
 
-	return sb.String(), nil
+// renderGrid draws an already cropped image into a grid of exactly w x h cells with the renderer opts.Mode selects.
+func renderGrid(m image.Image, w, h int, r ramp, opts Options) (*Grid, error) {
+	switch opts.Mode {
+	case ModeBraille:
+		return renderBraille(m, w, h, opts), nil
+	case ModeHalfBlock:
+		return renderHalfBlock(m, w, h, opts), nil
+	case ModeEdges:
+		return renderEdges(m, w, h, r, opts), nil
+	case ModeGlyph:
+		return renderGlyphs(m, w, h, r, opts)
+	default:
+		return renderRamp(m, w, h, r, opts), nil
+	}
+}
+
+// shrink scales the output size down proportionally to how far over the character limit it came out, making sure to actually
+// get smaller every time.
+func shrink(w, h, n, maxChars int) (int, int, error) {
+	scale := math.Sqrt(float64(maxChars)/float64(n)) * 0.95
+	w, h = min(int(float64(w)*scale), w-1), min(int(float64(h)*scale), h-1)
+	if w < 1 || h < 1 {
+		return 0, 0, fmt.Errorf("output can't fit in %d characters", maxChars)
+	}
+	return w, h, nil
+}
+
+// colorMode is the color mode the output will actually be encoded with, since some renderers don't work without color.
+func (opts Options) colorMode() ColorMode {
+	if opts.Mode == ModeHalfBlock && opts.Color == ColorNone {
+		return ColorTrue
+	}
+	return opts.Color
+}
+
+// renderRamp resamples the image down to one luminance value per character, then fills the grid with corresponding characters
+// from the ramp.
+func renderRamp(m image.Image, w, h int, r ramp, opts Options) *Grid {
+	p := sampleGray(m, w, h, opts)
+	dither(p, len(r), opts.Dither)
+	colors := colorPlanes(m, w, h, opts)
+	g := newGrid(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			g.set(x, y, Cell{Rune: r.glyph(p.at(x, y)), FG: colors.at(x, y)})
+		}
+	}
+	return g
+}
+
+// colorPlanes samples one color per cell when the output will be colored, and otherwise returns nil planes whose at method
+// returns nil.
+func colorPlanes(m image.Image, w, h int, opts Options) *rgbPlanes {
+	if opts.Color == ColorNone {
+		return nil
+	}
+	planes := sampleColor(m, w, h, opts)
+	return &planes
 }
@@ -0,0 +1,99 @@
+package asciify
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"testing"
+)
+
+// benchImage builds a photo-sized image with smooth gradients and hard edges, so every renderer has something to chew on.
+func benchImage(w, h int) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := float64(x-w/2), float64(y-h/2)
+			v := uint8(128 + 127*math.Sin(math.Hypot(dx, dy)/40))
+			if (x/64+y/64)%2 == 0 {
+				v = 255 - v
+			}
+			m.SetRGBA(x, y, color.RGBA{v, uint8(x), uint8(y), 0xff})
+		}
+	}
+	return m
+}
+
+// benchYCbCr converts the bench image to 4:2:0 YCbCr, the way decoded jpegs come out.
+func benchYCbCr(w, h int) *image.YCbCr {
+	src := benchImage(w, h)
+	m := image.NewYCbCr(src.Bounds(), image.YCbCrSubsampleRatio420)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := src.RGBAAt(x, y)
+			yy, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+			m.Y[m.YOffset(x, y)] = yy
+			m.Cb[m.COffset(x, y)] = cb
+			m.Cr[m.COffset(x, y)] = cr
+		}
+	}
+	return m
+}
+
+// genericImage hides the concrete type of an image, forcing every pixel to be read through At.
+type genericImage struct {
+	image.Image
+}
+
+func benchmarkRender(b *testing.B, m image.Image, opts Options) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Render(m, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+var (
+	rampOptions  = Options{MaxWidth: 256, MaxHeight: 128, Resample: ResampleBox}
+	glyphOptions = Options{MaxWidth: 256, MaxHeight: 128, Resample: ResampleBox, Mode: ModeGlyph}
+)
+
+func parallelOptions(opts Options) Options {
+	opts.Workers = runtime.GOMAXPROCS(0)
+	return opts
+}
+
+func BenchmarkRenderRamp(b *testing.B) {
+	benchmarkRender(b, benchImage(1920, 1080), rampOptions)
+}
+
+func BenchmarkRenderRampGeneric(b *testing.B) {
+	benchmarkRender(b, genericImage{benchImage(1920, 1080)}, rampOptions)
+}
+
+func BenchmarkRenderRampYCbCr(b *testing.B) {
+	benchmarkRender(b, benchYCbCr(1920, 1080), rampOptions)
+}
+
+func BenchmarkRenderRampYCbCrGeneric(b *testing.B) {
+	benchmarkRender(b, genericImage{benchYCbCr(1920, 1080)}, rampOptions)
+}
+
+func BenchmarkRenderRampColor(b *testing.B) {
+	opts := rampOptions
+	opts.Color = ColorTrue
+	benchmarkRender(b, benchImage(1920, 1080), opts)
+}
+
+func BenchmarkRenderRampParallel(b *testing.B) {
+	benchmarkRender(b, benchImage(1920, 1080), parallelOptions(rampOptions))
+}
+
+func BenchmarkRenderGlyph(b *testing.B) {
+	benchmarkRender(b, benchImage(1920, 1080), glyphOptions)
+}
+
+func BenchmarkRenderGlyphParallel(b *testing.B) {
+	benchmarkRender(b, benchImage(1920, 1080), parallelOptions(glyphOptions))
+}
@@ -0,0 +1,68 @@
+package asciify
+
+import (
+	"image"
+)
+
+const (
+	// brailleBlank is the empty pattern at the start of the Unicode braille block, U+2800 through U+28FF
+	brailleBlank = 0x2800
+	// brailleCellWidth and brailleCellHeight are the dots in each braille character
+	brailleCellWidth, brailleCellHeight = 2, 4
+)
+
+// brailleDots maps a dot's position in a cell to its bit in the pattern. The numbering dates back to 6-dot braille, so the
+// bottom row (dots 7 and 8) was tacked onto the end.
+var brailleDots = [brailleCellHeight][brailleCellWidth]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// renderBraille samples the image at 2x4 dots per cell, decides which dots are raised by thresholding or dithering down to 2
+// levels, and packs each cell's dots into a braille character.
+func renderBraille(m image.Image, w, h int, opts Options) *Grid {
+	p := sampleGray(m, w*brailleCellWidth, h*brailleCellHeight, opts)
+	threshold := opts.Threshold
+	if threshold <= 0 || threshold >= 1 {
+		threshold = mean(p)
+	}
+	if opts.Dither != DitherNone {
+		// dithered dots are already fully on or off
+		dither(p, 2, opts.Dither)
+		threshold = 0.5
+	}
+	// raised dots are drawn in the text color, so they stand for light pixels on a dark background and vice versa
+	raiseLight := (opts.Background == BackgroundDark) != opts.Invert
+
+	colors := colorPlanes(m, w, h, opts)
+	g := newGrid(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			cell := rune(brailleBlank)
+			for dy, row := range brailleDots {
+				for dx, bit := range row {
+					light := p.at(x*brailleCellWidth+dx, y*brailleCellHeight+dy) >= threshold
+					if light == raiseLight {
+						cell |= bit
+					}
+				}
+			}
+			g.set(x, y, Cell{Rune: cell, FG: colors.at(x, y)})
+		}
+	}
+	return g
+}
+
+// mean averages every value in a plane.
+func mean(p plane) float32 {
+	if len(p.pix) == 0 {
+		return 0.5
+	}
+	var sum float64
+	for _, v := range p.pix {
+		sum += float64(v)
+	}
+	return float32(sum / float64(len(p.pix)))
+}
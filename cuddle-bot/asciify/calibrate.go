@@ -0,0 +1,109 @@
+package asciify
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"slices"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// CalibrationChars are the printable ASCII characters, minus the backtick, which would end a Discord code block early.
+const CalibrationChars = " !\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_abcdefghijklmnopqrstuvwxyz{|}~"
+
+// GlyphCoverage is a character and the fraction of its cell that a font covers with ink.
+type GlyphCoverage struct {
+	Rune     rune
+	Coverage float64
+}
+
+// MeasureCoverage draws each character in its own cell with the face and measures how much of the cell it inks, from 0 for
+// blank to 1 for solid. Characters the face doesn't have are skipped, rather than measuring whatever placeholder it draws
+// instead.
+func MeasureCoverage(face font.Face, chars string) []GlyphCoverage {
+	metrics := face.Metrics()
+	advance, _ := face.GlyphAdvance('0')
+	cell := image.Rect(0, 0, advance.Ceil(), metrics.Height.Ceil())
+	dst := image.NewAlpha(cell)
+	drawer := &font.Drawer{Dst: dst, Src: image.Opaque, Face: face}
+
+	var coverages []GlyphCoverage
+	seen := map[rune]bool{}
+	for _, r := range chars {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		if _, ok := face.GlyphAdvance(r); !ok && r != ' ' {
+			continue
+		}
+
+		draw.Draw(dst, cell, image.Transparent, image.Point{}, draw.Src)
+		drawer.Dot = fixed.P(0, metrics.Ascent.Ceil())
+		drawer.DrawString(string(r))
+		var ink float64
+		for _, a := range dst.Pix {
+			ink += float64(a) / 255
+		}
+		coverages = append(coverages, GlyphCoverage{Rune: r, Coverage: ink / float64(len(dst.Pix))})
+	}
+	return coverages
+}
+
+// Calibrate builds a ramp for a font out of the candidate characters, ordered from most to least ink like DefaultRamp. If n is
+// positive, only n characters are picked, chosen so their coverages are as evenly spaced as possible from the darkest to the
+// lightest candidate; otherwise every candidate is used.
+func Calibrate(face font.Face, chars string, n int) (string, error) {
+	coverages := MeasureCoverage(face, chars)
+	if len(coverages) < 2 {
+		return "", errors.New("calibration needs at least 2 characters the font can draw")
+	}
+	slices.SortStableFunc(coverages, func(a, b GlyphCoverage) int {
+		if a.Coverage > b.Coverage {
+			return -1
+		} else if a.Coverage < b.Coverage {
+			return 1
+		}
+		return 0
+	})
+	if n <= 0 || n >= len(coverages) {
+		return runesOf(coverages), nil
+	}
+	n = max(n, 2)
+
+	// walk evenly spaced targets from darkest to lightest, taking the closest remaining character for each one
+	darkest, lightest := coverages[0].Coverage, coverages[len(coverages)-1].Coverage
+	picked := make([]GlyphCoverage, 0, n)
+	next := 0
+	for i := 0; i < n; i++ {
+		target := darkest + (lightest-darkest)*float64(i)/float64(n-1)
+		// leave enough candidates behind for the remaining targets
+		last := len(coverages) - (n - i)
+		best := next
+		for j := next; j <= last; j++ {
+			if abs(coverages[j].Coverage-target) < abs(coverages[best].Coverage-target) {
+				best = j
+			}
+		}
+		picked = append(picked, coverages[best])
+		next = best + 1
+	}
+	return runesOf(picked), nil
+}
+
+func runesOf(coverages []GlyphCoverage) string {
+	r := make([]rune, len(coverages))
+	for i, c := range coverages {
+		r[i] = c.Rune
+	}
+	return string(r)
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
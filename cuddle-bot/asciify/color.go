@@ -0,0 +1,279 @@
+package asciify
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// ColorMode selects whether and how cell colors are encoded as ANSI escape sequences.
+type ColorMode int
+
+const (
+	// ColorNone writes plain text.
+	ColorNone ColorMode = iota
+	// ColorDiscord quantizes each cell to the 8 foreground and 8 background colors Discord renders in ```ansi code blocks.
+	ColorDiscord
+	// Color256 quantizes each cell to the xterm 256-color palette, for terminals without truecolor support.
+	Color256
+	// ColorTrue writes 24-bit colors, for terminals with truecolor support.
+	ColorTrue
+)
+
+var colorNames = map[string]ColorMode{
+	"none":      ColorNone,
+	"discord":   ColorDiscord,
+	"256":       Color256,
+	"truecolor": ColorTrue,
+}
+
+// ParseColorMode looks up a color mode by its lowercase name, e.g. "discord".
+func ParseColorMode(name string) (ColorMode, error) {
+	if mode, ok := colorNames[name]; ok {
+		return mode, nil
+	}
+	return ColorNone, fmt.Errorf("unknown color mode (%s)", name)
+}
+
+const sgrReset = "\x1b[0m"
+
+// paletteColor is an SGR parameter and the color Discord draws for it.
+type paletteColor struct {
+	code int
+	c    color.RGBA
+}
+
+// Discord's ```ansi palette isn't the usual terminal one: it's based on Solarized, and the background colors don't line up
+// with the foreground colors of the same index.
+var (
+	discordFG = []paletteColor{
+		{30, color.RGBA{0x4f, 0x54, 0x5c, 0xff}},
+		{31, color.RGBA{0xdc, 0x32, 0x2f, 0xff}},
+		{32, color.RGBA{0x85, 0x99, 0x00, 0xff}},
+		{33, color.RGBA{0xb5, 0x89, 0x00, 0xff}},
+		{34, color.RGBA{0x26, 0x8b, 0xd2, 0xff}},
+		{35, color.RGBA{0xd3, 0x36, 0x82, 0xff}},
+		{36, color.RGBA{0x2a, 0xa1, 0x98, 0xff}},
+		{37, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+	}
+	discordBG = []paletteColor{
+		{40, color.RGBA{0x00, 0x2b, 0x36, 0xff}},
+		{41, color.RGBA{0xcb, 0x4b, 0x16, 0xff}},
+		{42, color.RGBA{0x58, 0x6e, 0x75, 0xff}},
+		{43, color.RGBA{0x65, 0x7b, 0x83, 0xff}},
+		{44, color.RGBA{0x83, 0x94, 0x96, 0xff}},
+		{45, color.RGBA{0x6c, 0x71, 0xc4, 0xff}},
+		{46, color.RGBA{0x93, 0xa1, 0xa1, 0xff}},
+		{47, color.RGBA{0xfd, 0xf6, 0xe3, 0xff}},
+	}
+)
+
+// xtermLevels are the channel values of the 6x6x6 color cube in the xterm 256-color palette
+var xtermLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+// sgr returns the SGR parameters that select c as the foreground (or background) color in this mode, or "" for the default.
+func (mode ColorMode) sgr(c color.Color, background bool) string {
+	if c == nil {
+		return ""
+	}
+	switch mode {
+	case ColorDiscord:
+		palette := discordFG
+		if background {
+			palette = discordBG
+		}
+		return strconv.Itoa(nearestPalette(palette, c).code)
+	case Color256:
+		prefix := "38;5;"
+		if background {
+			prefix = "48;5;"
+		}
+		return prefix + strconv.Itoa(xterm256(c))
+	case ColorTrue:
+		rgba := toRGBA(c)
+		prefix := "38;2;"
+		if background {
+			prefix = "48;2;"
+		}
+		return fmt.Sprintf("%s%d;%d;%d", prefix, rgba.R, rgba.G, rgba.B)
+	default:
+		return ""
+	}
+}
+
+// display returns the color c will actually be shown as in this mode, or def if c is nil or the mode has no color.
+func (mode ColorMode) display(c color.Color, background bool, def color.RGBA) color.RGBA {
+	if c == nil {
+		return def
+	}
+	switch mode {
+	case ColorDiscord:
+		palette := discordFG
+		if background {
+			palette = discordBG
+		}
+		return nearestPalette(palette, c).c
+	case Color256:
+		return xterm256Color(xterm256(c))
+	case ColorTrue:
+		return toRGBA(c)
+	default:
+		return def
+	}
+}
+
+// writeSGR writes a single escape sequence covering whichever of the foreground and background changed, and returns the new
+// current state. Going back to a default color requires a reset, which also drops the other color.
+func writeSGR(sb *strings.Builder, curFG, curBG, fg, bg string) (string, string) {
+	if fg == curFG && bg == curBG {
+		return curFG, curBG
+	}
+	var params []string
+	if (fg == "" && curFG != "") || (bg == "" && curBG != "") {
+		params = append(params, "0")
+		curFG, curBG = "", ""
+	}
+	if fg != curFG {
+		params = append(params, fg)
+	}
+	if bg != curBG {
+		params = append(params, bg)
+	}
+	if len(params) > 0 {
+		sb.WriteString("\x1b[" + strings.Join(params, ";") + "m")
+	}
+	return fg, bg
+}
+
+// nearestPalette finds the palette entry closest to c.
+func nearestPalette(palette []paletteColor, c color.Color) paletteColor {
+	rgba := toRGBA(c)
+	best, bestDist := palette[0], colorDistance(rgba, palette[0].c)
+	for _, p := range palette[1:] {
+		if d := colorDistance(rgba, p.c); d < bestDist {
+			best, bestDist = p, d
+		}
+	}
+	return best
+}
+
+// colorDistance is the "redmean" approximation of perceptual distance, which is much better than plain RGB distance for how
+// little more it costs.
+func colorDistance(a, b color.RGBA) float64 {
+	rmean := (float64(a.R) + float64(b.R)) / 2
+	dr, dg, db := float64(a.R)-float64(b.R), float64(a.G)-float64(b.G), float64(a.B)-float64(b.B)
+	return (2+rmean/256)*dr*dr + 4*dg*dg + (2+(255-rmean)/256)*db*db
+}
+
+// xterm256 quantizes c to the 6x6x6 color cube or the 24-step gray ramp of the xterm 256-color palette, whichever is closer.
+func xterm256(c color.Color) int {
+	rgba := toRGBA(c)
+	levels := xtermLevels
+	nearestLevel := func(v uint8) int {
+		best := 0
+		for i, l := range levels {
+			if absDiff(v, l) < absDiff(v, levels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	r, g, b := nearestLevel(rgba.R), nearestLevel(rgba.G), nearestLevel(rgba.B)
+	cube := color.RGBA{levels[r], levels[g], levels[b], 0xff}
+
+	avg := (int(rgba.R) + int(rgba.G) + int(rgba.B)) / 3
+	grayIndex := min(max((avg-8+5)/10, 0), 23)
+	grayLevel := uint8(8 + grayIndex*10)
+	gray := color.RGBA{grayLevel, grayLevel, grayLevel, 0xff}
+
+	if colorDistance(rgba, gray) < colorDistance(rgba, cube) {
+		return 232 + grayIndex
+	}
+	return 16 + 36*r + 6*g + b
+}
+
+// xterm256Color is the color of an index in the xterm 256-color palette, for indices from the color cube or the gray ramp.
+func xterm256Color(i int) color.RGBA {
+	if i >= 232 {
+		v := uint8(8 + (i-232)*10)
+		return color.RGBA{v, v, v, 0xff}
+	}
+	levels := xtermLevels
+	i -= 16
+	return color.RGBA{levels[i/36], levels[i/6%6], levels[i%6], 0xff}
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+func toRGBA(c color.Color) color.RGBA {
+	return color.RGBAModel.Convert(c).(color.RGBA)
+}
+
+// rgbPlanes holds the red, green and blue channels of a resampled image.
+type rgbPlanes [3]plane
+
+// sampleColor resamples the color of m down (or up) to w x h planes, one per channel, blending pixels that aren't opaque over
+// the matte. Only the tone adjustments that work on each sample on its own are applied, since equalizing the channels
+// separately would shift the colors.
+func sampleColor(m image.Image, w, h int, opts Options) rgbPlanes {
+	rgb := pixelRGB(m, opts.matte())
+	var planes rgbPlanes
+	for i := range planes {
+		planes[i] = resample(m.Bounds(), w, h, opts.Resample, opts.Workers, func(x, y int) float32 {
+			r, g, b := rgb(x, y)
+			return [3]float32{r, g, b}[i]
+		})
+		opts.Tone.applyPointwise(planes[i])
+	}
+	return planes
+}
+
+// at returns the resampled color at (x, y), or nil if no colors were sampled.
+func (p *rgbPlanes) at(x, y int) color.Color {
+	if p == nil {
+		return nil
+	}
+	return p.rgba(x, y)
+}
+
+// rgba returns the resampled color at (x, y).
+func (p *rgbPlanes) rgba(x, y int) color.RGBA {
+	return color.RGBA{
+		R: uint8(p[0].at(x, y)*255 + 0.5),
+		G: uint8(p[1].at(x, y)*255 + 0.5),
+		B: uint8(p[2].at(x, y)*255 + 0.5),
+		A: 0xff,
+	}
+}
+
+// matte returns the normalized color to blend translucent pixels over.
+func (opts Options) matte() [3]float32 {
+	if opts.Matte == nil {
+		if opts.Background == BackgroundDark {
+			return [3]float32{0, 0, 0}
+		}
+		return [3]float32{1, 1, 1}
+	}
+	r, g, b, _ := opts.Matte.RGBA()
+	return [3]float32{float32(r) / 0xffff, float32(g) / 0xffff, float32(b) / 0xffff}
+}
+
+// flatten blends a color over the matte, returning normalized red, green and blue. Colors are alpha-premultiplied, so the
+// matte just fills in whatever the alpha leaves uncovered.
+func flatten(c color.Color, matte [3]float32) (float32, float32, float32) {
+	r, g, b, a := c.RGBA()
+	uncovered := 1 - float32(a)/0xffff
+	return float32(r)/0xffff + uncovered*matte[0], float32(g)/0xffff + uncovered*matte[1], float32(b)/0xffff + uncovered*matte[2]
+}
+
+// luminance weighs normalized red, green and blue the same way color.GrayModel does.
+func luminance(r, g, b float32) float32 {
+	return 0.299*r + 0.587*g + 0.114*b
+}
@@ -0,0 +1,113 @@
+package asciify
+
+import (
+	"fmt"
+)
+
+// Dither selects how luminance is spread across neighboring cells when it's quantized to the levels of a character ramp, which
+// trades a little noise for smooth gradients instead of visible bands.
+type Dither int
+
+const (
+	// DitherNone rounds every cell to its nearest ramp level.
+	DitherNone Dither = iota
+	// DitherFloydSteinberg diffuses all of each cell's quantization error to the 4 unvisited neighbors.
+	DitherFloydSteinberg
+	// DitherAtkinson diffuses 3/4 of each cell's quantization error over 6 neighbors, which keeps more contrast.
+	DitherAtkinson
+	// DitherBayer adds a fixed 4x4 threshold pattern before rounding, which doesn't crawl around between similar images.
+	DitherBayer
+)
+
+var ditherNames = map[string]Dither{
+	"none":           DitherNone,
+	"floydsteinberg": DitherFloydSteinberg,
+	"fs":             DitherFloydSteinberg,
+	"atkinson":       DitherAtkinson,
+	"bayer":          DitherBayer,
+}
+
+// ParseDither looks up a dithering mode by its lowercase name, e.g. "atkinson".
+func ParseDither(name string) (Dither, error) {
+	if mode, ok := ditherNames[name]; ok {
+		return mode, nil
+	}
+	return DitherNone, fmt.Errorf("unknown dithering mode (%s)", name)
+}
+
+// diffusion is one entry of an error diffusion kernel, relative to the current cell.
+type diffusion struct {
+	dx, dy int
+	w      float32
+}
+
+var (
+	floydSteinberg = []diffusion{
+		{1, 0, 7.0 / 16}, {-1, 1, 3.0 / 16}, {0, 1, 5.0 / 16}, {1, 1, 1.0 / 16},
+	}
+	atkinson = []diffusion{
+		{1, 0, 1.0 / 8}, {2, 0, 1.0 / 8}, {-1, 1, 1.0 / 8}, {0, 1, 1.0 / 8}, {1, 1, 1.0 / 8}, {0, 2, 1.0 / 8},
+	}
+	bayer4 = [4][4]float32{
+		{0, 8, 2, 10},
+		{12, 4, 14, 6},
+		{3, 11, 1, 9},
+		{15, 7, 13, 5},
+	}
+)
+
+// dither quantizes a plane in place to the given number of evenly spaced levels, so every value it leaves behind lands exactly
+// on a level. Later stages map those values straight to ramp characters or dots.
+func dither(p plane, levels int, mode Dither) {
+	if levels < 2 {
+		return
+	}
+	steps := float32(levels - 1)
+	quantize := func(v float32) float32 {
+		return float32(int(clamp01(v)*steps+0.5)) / steps
+	}
+
+	switch mode {
+	case DitherFloydSteinberg:
+		diffuse(p, quantize, floydSteinberg)
+	case DitherAtkinson:
+		diffuse(p, quantize, atkinson)
+	case DitherBayer:
+		// spread the threshold over one level's worth of luminance, centered so the average brightness doesn't shift
+		for y := 0; y < p.h; y++ {
+			for x := 0; x < p.w; x++ {
+				offset := ((bayer4[y%4][x%4]+0.5)/16 - 0.5) / steps
+				p.set(x, y, quantize(p.at(x, y)+offset))
+			}
+		}
+	default:
+		for i, v := range p.pix {
+			p.pix[i] = quantize(v)
+		}
+	}
+}
+
+// diffuse walks the plane in serpentine order, pushing each cell's quantization error onto its neighbors. Alternating the
+// direction of each row keeps the error from piling up on one side.
+func diffuse(p plane, quantize func(float32) float32, kernel []diffusion) {
+	for y := 0; y < p.h; y++ {
+		reverse := y%2 == 1
+		for i := 0; i < p.w; i++ {
+			x, dir := i, 1
+			if reverse {
+				x, dir = p.w-1-i, -1
+			}
+			old := p.at(x, y)
+			q := quantize(old)
+			p.set(x, y, q)
+			e := old - q
+			for _, d := range kernel {
+				nx, ny := x+d.dx*dir, y+d.dy
+				if nx < 0 || nx >= p.w || ny >= p.h {
+					continue
+				}
+				p.set(nx, ny, p.at(nx, ny)+e*d.w)
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package asciify
+
+import (
+	"image"
+	"math"
+)
+
+const (
+	// edgeCellWidth and edgeCellHeight are the samples taken per cell for edge detection, in roughly the shape of a character
+	// cell so that the samples themselves are about square and angles come out right
+	edgeCellWidth, edgeCellHeight = 4, 8
+)
+
+// edgeCell is the combined gradient of all the samples in one cell.
+type edgeCell struct {
+	// magnitude is the mean gradient magnitude of the samples in the cell
+	magnitude float64
+	// angle is the dominant gradient direction in radians, in (-pi/2, pi/2], where 0 points right and positive angles point
+	// down and to the right
+	angle float64
+	// low is how far down the cell the gradient is concentrated, from 0 at the top to 1 at the bottom
+	low float64
+}
+
+// renderEdges finds edges Canny-style, with a Sobel pass over a finely sampled grayscale image, non-maximum suppression and
+// hysteresis thresholding on the cells, then draws the surviving edges with the line glyph closest to their direction. Cells
+// without an edge are blank, or filled from the ramp when blending.
+func renderEdges(m image.Image, w, h int, r ramp, opts Options) *Grid {
+	p := sampleGray(m, w*edgeCellWidth, h*edgeCellHeight, opts)
+	cells := edgeCells(p, w, h)
+	edges := cannyCells(cells, w, h, opts.Threshold)
+
+	var tones plane
+	if opts.Blend {
+		tones = sampleGray(m, w, h, opts)
+		dither(tones, len(r), opts.Dither)
+	}
+	colors := colorPlanes(m, w, h, opts)
+	g := newGrid(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := Cell{Rune: ' ', FG: colors.at(x, y)}
+			if edges[y*w+x] {
+				c.Rune = edgeGlyph(cells[y*w+x])
+			} else if opts.Blend {
+				c.Rune = r.glyph(tones.at(x, y))
+			}
+			g.set(x, y, c)
+		}
+	}
+	return g
+}
+
+// edgeCells runs a Sobel operator over the plane and sums each cell's structure tensor, which gives a dominant direction that
+// isn't thrown off by gradients on opposite sides of a thin line pointing in opposite directions.
+func edgeCells(p plane, w, h int) []edgeCell {
+	cells := make([]edgeCell, w*h)
+	at := func(x, y int) float64 {
+		return float64(p.at(min(max(x, 0), p.w-1), min(max(y, 0), p.h-1)))
+	}
+	for cy := 0; cy < h; cy++ {
+		for cx := 0; cx < w; cx++ {
+			var jxx, jyy, jxy, sum, weightedY float64
+			for sy := 0; sy < edgeCellHeight; sy++ {
+				for sx := 0; sx < edgeCellWidth; sx++ {
+					x, y := cx*edgeCellWidth+sx, cy*edgeCellHeight+sy
+					gx := at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)
+					gy := at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)
+					jxx += gx * gx
+					jyy += gy * gy
+					jxy += gx * gy
+					mag := math.Hypot(gx, gy)
+					sum += mag
+					weightedY += mag * (float64(sy) + 0.5)
+				}
+			}
+			c := edgeCell{
+				magnitude: sum / (edgeCellWidth * edgeCellHeight),
+				angle:     0.5 * math.Atan2(2*jxy, jxx-jyy),
+				low:       0.5,
+			}
+			if sum > 0 {
+				c.low = weightedY / sum / edgeCellHeight
+			}
+			cells[cy*w+cx] = c
+		}
+	}
+	return cells
+}
+
+// cannyCells decides which cells hold an edge. Cells that aren't the strongest along their gradient direction are thinned out,
+// then cells above the high threshold are kept along with any connected cells above the low threshold. The high threshold is
+// a fraction of the strongest edge if one was given, or one standard deviation above the mean otherwise.
+func cannyCells(cells []edgeCell, w, h int, threshold float32) []bool {
+	magnitude := func(x, y int) float64 {
+		if x < 0 || x >= w || y < 0 || y >= h {
+			return 0
+		}
+		return cells[y*w+x].magnitude
+	}
+
+	// non-maximum suppression, comparing each cell with its neighbors on either side along the gradient
+	thin := make([]float64, len(cells))
+	var strongest, sum, sumSquares float64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := cells[y*w+x]
+			dx, dy := int(math.Round(math.Cos(c.angle))), int(math.Round(math.Sin(c.angle)))
+			if c.magnitude >= magnitude(x+dx, y+dy) && c.magnitude >= magnitude(x-dx, y-dy) {
+				thin[y*w+x] = c.magnitude
+			}
+			strongest = math.Max(strongest, c.magnitude)
+			sum += c.magnitude
+			sumSquares += c.magnitude * c.magnitude
+		}
+	}
+	n := float64(len(cells))
+	mean := sum / n
+	high := mean + math.Sqrt(math.Max(sumSquares/n-mean*mean, 0))
+	if threshold > 0 && threshold < 1 {
+		high = float64(threshold) * strongest
+	}
+	low := high / 2
+	if high <= 0 {
+		return make([]bool, len(cells))
+	}
+
+	// hysteresis, flood filling out from the strong edges through the weak ones
+	edges := make([]bool, len(cells))
+	var stack []int
+	for i, v := range thin {
+		if v >= high {
+			edges[i] = true
+			stack = append(stack, i)
+		}
+	}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		x, y := i%w, i/w
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := x+dx, y+dy
+				if nx < 0 || nx >= w || ny < 0 || ny >= h {
+					continue
+				}
+				if j := ny*w + nx; !edges[j] && thin[j] >= low {
+					edges[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+	}
+	return edges
+}
+
+// edgeGlyph picks the line character that runs perpendicular to the cell's gradient. Horizontal edges near the bottom of a
+// cell use an underscore, which sits lower than a hyphen.
+func edgeGlyph(c edgeCell) rune {
+	degrees := c.angle * 180 / math.Pi
+	switch {
+	case math.Abs(degrees) < 22.5:
+		return '|'
+	case degrees >= 22.5 && degrees <= 67.5:
+		return '/'
+	case degrees <= -22.5 && degrees >= -67.5:
+		return '\\'
+	case c.low > 0.7:
+		return '_'
+	default:
+		return '-'
+	}
+}
@@ -0,0 +1,60 @@
+package asciify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"slices"
+	"strings"
+
+	// import for initialization side-effects
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// Formats lists the image formats asciify can decode, as named by image.DecodeConfig.
+var Formats = []string{"png", "jpeg", "gif", "webp", "bmp", "tiff"}
+
+// ErrUnsupportedFormat is returned when an image's content doesn't match any of the supported Formats.
+var ErrUnsupportedFormat = fmt.Errorf("image must be one of %s", strings.Join(Formats, ", "))
+
+// DefaultMaxPixels is the largest image, in total pixels, that will be decoded when Options.MaxPixels isn't set. Decoded
+// images take from about 1.5 bytes per pixel for a typical jpeg up to 8 for a 16 bit png, so this keeps a single image to
+// 400 MB at most, and usually well under 200 MB.
+const DefaultMaxPixels = 50_000_000
+
+// ErrTooLarge is returned, wrapped with the offending size, when an image has more pixels than allowed.
+var ErrTooLarge = errors.New("image is too large")
+
+// checkSize returns an ErrTooLarge if an image of the given config would be too big to decode safely. A tiny compressed file
+// can claim enormous dimensions, so this has to happen before decoding allocates anything.
+func checkSize(config image.Config, opts Options) error {
+	maxPixels := opts.MaxPixels
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+	if config.Width < 0 || config.Height < 0 || int64(config.Width)*int64(config.Height) > int64(maxPixels) {
+		return fmt.Errorf("%w: %dx%d is over the limit of %d pixels", ErrTooLarge, config.Width, config.Height, maxPixels)
+	}
+	return nil
+}
+
+// Sniff identifies an image's format from its content rather than its name or a declared content type, so a jpeg saved as
+// .png is still decoded as a jpeg. It returns a reader that replays the sniffed bytes followed by the rest of the stream, for
+// decoding the whole image afterward.
+func Sniff(r io.Reader) (string, image.Config, io.Reader, error) {
+	var sniffed bytes.Buffer
+	config, format, err := image.DecodeConfig(io.TeeReader(r, &sniffed))
+	replay := io.MultiReader(&sniffed, r)
+	if errors.Is(err, image.ErrFormat) || (err == nil && !slices.Contains(Formats, format)) {
+		return format, config, replay, ErrUnsupportedFormat
+	}
+	return format, config, replay, err
+}
@@ -0,0 +1,211 @@
+package asciify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+// Animation is an asciified animated image.
+type Animation struct {
+	// Frames holds the text of each frame, in order.
+	Frames []string
+	// Delays holds how long each frame should be shown before the next one.
+	Delays []time.Duration
+}
+
+// AsciifyGIF decodes every frame of a GIF from a stream and converts them with AsciifyGIFImage. GIFs are rejected with
+// ErrTooLarge before any frames are decoded if their logical screen has more than opts.MaxPixels pixels, or if all their
+// frames together would take more memory to decode than a still image of that many pixels.
+func AsciifyGIF(r io.Reader, opts Options, maxFrames int) (*Animation, error) {
+	format, config, r, err := Sniff(r)
+	if err != nil {
+		return nil, err
+	}
+	if format != "gif" {
+		return nil, fmt.Errorf("image must be a gif, not %s", format)
+	}
+	if err := checkSize(config, opts); err != nil {
+		return nil, err
+	}
+	// every frame is decoded up front, so the frames have to be counted before any of them are
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFrames(data, opts); err != nil {
+		return nil, err
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return AsciifyGIFImage(g, opts, maxFrames)
+}
+
+// AsciifyGIFImage composites each frame of a GIF over the ones before it, the way a browser would play it, and converts the
+// result the same way AsciifyImage does. Every frame comes out the same size, so the animation doesn't jump around when some
+// frames take more escape sequences than others; with opts.MaxChars set, that's the largest size every frame fits at. If
+// maxFrames is positive and there are more frames than that, frames are dropped evenly across the animation, and the delays
+// of dropped frames are added to the frames before them so the animation keeps the same pace.
+func AsciifyGIFImage(g *gif.GIF, opts Options, maxFrames int) (*Animation, error) {
+	if len(g.Image) == 0 {
+		return nil, errors.New("gif has no frames")
+	}
+	if opts.MaxWidth < 1 || opts.MaxHeight < 1 {
+		return nil, errors.New("ascii max size must be wider/taller than 0")
+	}
+	r, err := newRamp(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// decide which frames to keep up front, since every frame still has to be composited to get the later ones right
+	keep := make([]bool, len(g.Image))
+	kept := len(g.Image)
+	if maxFrames > 0 && kept > maxFrames {
+		kept = maxFrames
+	}
+	for i := 0; i < kept; i++ {
+		keep[i*len(g.Image)/kept] = true
+	}
+
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		bounds = g.Image[0].Bounds()
+	}
+	src, w, h, err := layout(bounds, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// the frames aren't kept around between tries, so a frame that's too long shrinks all of them and starts over
+	for {
+		anim, longest, err := renderFrames(g, keep, bounds, src, w, h, r, opts)
+		if err != nil {
+			return nil, err
+		}
+		if opts.MaxChars <= 0 || longest <= opts.MaxChars {
+			return anim, nil
+		}
+		if w, h, err = shrink(w, h, longest, opts.MaxChars); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// renderFrames composites every frame of a GIF on a canvas with the given bounds, and renders the kept ones from the src part
+// of it at exactly w x h cells. It also returns the length of the longest frame's text.
+func renderFrames(g *gif.GIF, keep []bool, bounds, src image.Rectangle, w, h int, r ramp, opts Options) (*Animation, int, error) {
+	canvas := image.NewRGBA(bounds)
+	var previous *image.RGBA
+
+	anim := &Animation{}
+	longest := 0
+	for i, frame := range g.Image {
+		disposal := byte(gif.DisposalNone)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		delay := time.Duration(0)
+		if i < len(g.Delay) {
+			delay = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+		if keep[i] {
+			grid, err := renderGrid(crop(canvas, src), w, h, r, opts)
+			if err != nil {
+				return nil, 0, err
+			}
+			text := grid.Text(opts.colorMode())
+			longest = max(longest, utf8.RuneCountInString(text))
+			anim.Frames = append(anim.Frames, text)
+			anim.Delays = append(anim.Delays, delay)
+		} else {
+			anim.Delays[len(anim.Delays)-1] += delay
+		}
+
+		// clean up after the frame so the next one is drawn over the right thing
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			if previous != nil {
+				canvas = previous
+			}
+		}
+	}
+	return anim, longest, nil
+}
+
+// checkFrames returns an ErrTooLarge if decoding every frame of a GIF would take too much memory. A GIF can repeat a tiny
+// compressed frame hundreds of times, so the frame sizes are read from the stream's block structure without decoding any
+// pixels. Decoded frames take 1 byte per pixel rather than the 4 a still image does, so the frames together get 4 times the
+// pixels of opts.MaxPixels. Anything that doesn't parse is left for the decoder to reject.
+func checkFrames(data []byte, opts Options) error {
+	maxPixels := opts.MaxPixels
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+	budget := 4 * int64(maxPixels)
+
+	// skip the header, logical screen descriptor, and global color table
+	const headerSize, screenSize = 6, 7
+	if len(data) < headerSize+screenSize {
+		return nil
+	}
+	i := headerSize + screenSize
+	if flags := data[headerSize+4]; flags&0x80 != 0 {
+		i += 3 << (flags&0x07 + 1)
+	}
+
+	var pixels int64
+	frames := 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension: label, then data sub-blocks
+			i += 2
+		case 0x2c: // image descriptor: position, size, flags, optional local color table, LZW code size, then data sub-blocks
+			if i+10 > len(data) {
+				return nil
+			}
+			width := int64(data[i+5]) | int64(data[i+6])<<8
+			height := int64(data[i+7]) | int64(data[i+8])<<8
+			pixels += width * height
+			frames++
+			if pixels > budget {
+				return fmt.Errorf("%w: %d frames are over the limit of %d pixels", ErrTooLarge, frames, budget)
+			}
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			i++
+		default: // the trailer, or something the decoder will complain about
+			return nil
+		}
+		// skip data sub-blocks, each prefixed with its size, until an empty one
+		for i < len(data) && data[i] != 0 {
+			i += int(data[i]) + 1
+		}
+		i++
+	}
+	return nil
+}
+
+func cloneRGBA(m *image.RGBA) *image.RGBA {
+	clone := image.NewRGBA(m.Bounds())
+	copy(clone.Pix, m.Pix)
+	return clone
+}
@@ -0,0 +1,142 @@
+package asciify
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// glyphTileWidth and glyphTileHeight are the samples compared per cell in ModeGlyph, in roughly the shape of a character
+	// cell
+	glyphTileWidth, glyphTileHeight = 6, 12
+
+	// glyphMeanWeight is how much a difference in brightness counts against a character compared to a difference in shape
+	glyphMeanWeight = 4
+	// glyphFlatVariance is the variance below which a tile is considered flat, with no structure worth matching
+	glyphFlatVariance = 0.002
+)
+
+// glyphBitmap is a character drawn at tile size, with the statistics for matching it precomputed.
+type glyphBitmap struct {
+	r        rune
+	pix      []float32
+	mean     float32
+	variance float32
+}
+
+// renderGlyphs splits the image into cell-sized tiles and picks the ramp character whose drawn shape best matches each tile by
+// correlation, so edges and text inside the image stay legible instead of being averaged away. It's much
+// slower than ModeRamp, since every character is compared against every tile.
+func renderGlyphs(m image.Image, w, h int, r ramp, opts Options) (*Grid, error) {
+	face, err := opts.face()
+	if err != nil {
+		return nil, err
+	}
+	// raised ink is drawn in the text color, so it stands for light pixels on a dark background and vice versa
+	inkIsLight := (opts.Background == BackgroundDark) != opts.Invert
+	glyphs := glyphBitmaps(face, r, inkIsLight)
+
+	// even the densest character leaves plenty of its cell uncovered, so squeeze the image's luminance into the range the
+	// characters can actually show, the same way ModeRamp maps black and white to the ends of the ramp
+	lo, hi := glyphs[0].mean, glyphs[0].mean
+	for _, g := range glyphs[1:] {
+		lo, hi = min(lo, g.mean), max(hi, g.mean)
+	}
+
+	p := sampleGray(m, w*glyphTileWidth, h*glyphTileHeight, opts)
+	colors := colorPlanes(m, w, h, opts)
+	g := newGrid(w, h)
+	parallel(h, opts.Workers, func(top, bottom int) {
+		tile := make([]float32, glyphTileWidth*glyphTileHeight)
+		for y := top; y < bottom; y++ {
+			for x := 0; x < w; x++ {
+				for ty := 0; ty < glyphTileHeight; ty++ {
+					for tx := 0; tx < glyphTileWidth; tx++ {
+						tile[ty*glyphTileWidth+tx] = lo + p.at(x*glyphTileWidth+tx, y*glyphTileHeight+ty)*(hi-lo)
+					}
+				}
+				g.set(x, y, Cell{Rune: bestGlyph(tile, glyphs), FG: colors.at(x, y)})
+			}
+		}
+	})
+	return g, nil
+}
+
+// glyphBitmaps draws each character of the ramp with the face and shrinks it to tile size, as the luminance it would show on
+// screen.
+func glyphBitmaps(face font.Face, r ramp, inkIsLight bool) []glyphBitmap {
+	metrics := face.Metrics()
+	advance, _ := face.GlyphAdvance('0')
+	cell := image.Rect(0, 0, advance.Ceil(), metrics.Height.Ceil())
+	dst := image.NewAlpha(cell)
+	drawer := &font.Drawer{Dst: dst, Src: image.Opaque, Face: face}
+
+	glyphs := make([]glyphBitmap, 0, len(r))
+	seen := map[rune]bool{}
+	for _, c := range r {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		draw.Draw(dst, cell, image.Transparent, image.Point{}, draw.Src)
+		drawer.Dot = fixed.P(0, metrics.Ascent.Ceil())
+		drawer.DrawString(string(c))
+
+		p := resample(cell, glyphTileWidth, glyphTileHeight, ResampleBox, 1, func(x, y int) float32 {
+			ink := float32(dst.AlphaAt(x, y).A) / 255
+			if inkIsLight {
+				return ink
+			}
+			return 1 - ink
+		})
+		mean, variance := stats(p.pix)
+		glyphs = append(glyphs, glyphBitmap{r: c, pix: p.pix, mean: mean, variance: variance})
+	}
+	return glyphs
+}
+
+// bestGlyph returns the character that best matches the tile, scoring each one by the squared difference in brightness plus
+// how far it is from being perfectly correlated with the tile. Flat tiles have no structure to correlate with, so they're
+// matched by brightness alone, the same as ModeRamp would.
+func bestGlyph(tile []float32, glyphs []glyphBitmap) rune {
+	mean, variance := stats(tile)
+	flat := variance < glyphFlatVariance
+	best, bestCost := glyphs[0].r, float32(math.MaxFloat32)
+	for _, g := range glyphs {
+		cost := glyphMeanWeight * (mean - g.mean) * (mean - g.mean)
+		if !flat {
+			var correlation float32
+			if g.variance > 0 {
+				var covariance float32
+				for i, v := range tile {
+					covariance += (v - mean) * (g.pix[i] - g.mean)
+				}
+				covariance /= float32(len(tile))
+				correlation = covariance / float32(math.Sqrt(float64(variance*g.variance)))
+			}
+			cost += (1 - correlation) / 2
+		}
+		if cost < bestCost {
+			best, bestCost = g.r, cost
+		}
+	}
+	return best
+}
+
+// stats returns the mean and variance of a set of samples.
+func stats(pix []float32) (float32, float32) {
+	var sum float32
+	for _, v := range pix {
+		sum += v
+	}
+	mean := sum / float32(len(pix))
+	var variance float32
+	for _, v := range pix {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / float32(len(pix))
+}
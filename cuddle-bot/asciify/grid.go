@@ -0,0 +1,102 @@
+package asciify
+
+import (
+	"image/color"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Cell is one character of rendered output.
+type Cell struct {
+	Rune rune
+	// FG and BG are the colors sampled for the cell's text and background, or nil to use the display's defaults.
+	FG, BG color.Color
+}
+
+// Grid is a rendered image, laid out as rows of cells, which can be encoded as text or drawn by other writers.
+type Grid struct {
+	Width, Height int
+	Cells         []Cell
+}
+
+func newGrid(w, h int) *Grid {
+	return &Grid{Width: w, Height: h, Cells: make([]Cell, w*h)}
+}
+
+// At returns the cell in column x of row y.
+func (g *Grid) At(x, y int) Cell {
+	return g.Cells[y*g.Width+x]
+}
+
+func (g *Grid) set(x, y int, c Cell) {
+	g.Cells[y*g.Width+x] = c
+}
+
+// Text encodes the grid as lines of text, with ANSI escape sequences for the cell colors unless mode is ColorNone. Escape
+// sequences are only written when a color changes, so runs of the same color cost nothing extra.
+func (g *Grid) Text(mode ColorMode) string {
+	var sb strings.Builder
+	var fg, bg string
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			c := g.At(x, y)
+			if mode != ColorNone {
+				cfg, cbg := mode.sgr(c.FG, false), mode.sgr(c.BG, true)
+				fg, bg = writeSGR(&sb, fg, bg, cfg, cbg)
+			}
+			sb.WriteRune(c.Rune)
+		}
+		// some terminals paint a lingering background color all the way to the edge of the window
+		if bg != "" {
+			sb.WriteString(sgrReset)
+			fg, bg = "", ""
+		}
+		sb.WriteString("\n")
+	}
+	if fg != "" || bg != "" {
+		sb.WriteString(sgrReset)
+	}
+	return sb.String()
+}
+
+// WriteText encodes a grid as lines of text, the same as AsciifyImage returns, for writers that take any output format.
+func WriteText(w io.Writer, g *Grid, opts Options) error {
+	_, err := io.WriteString(w, g.Text(opts.colorMode()))
+	return err
+}
+
+// colorRun is a run of neighboring cells in a row that are displayed in the same colors.
+type colorRun struct {
+	x, n   int
+	text   string
+	fg, bg color.RGBA
+}
+
+// runs splits row y into runs of cells that are displayed in the same colors, resolving them with mode the same way Rasterize
+// does, and falling back to the default text and background colors for uncolored cells.
+func (g *Grid) runs(y int, mode ColorMode, defaults [2]color.RGBA) []colorRun {
+	var runs []colorRun
+	var sb strings.Builder
+	for x := 0; x < g.Width; x++ {
+		c := g.At(x, y)
+		fg, bg := mode.display(c.FG, false, defaults[0]), mode.display(c.BG, true, defaults[1])
+		if len(runs) > 0 && runs[len(runs)-1].fg == fg && runs[len(runs)-1].bg == bg {
+			sb.WriteRune(c.Rune)
+			continue
+		}
+		if len(runs) > 0 {
+			runs[len(runs)-1].text = sb.String()
+			sb.Reset()
+		}
+		runs = append(runs, colorRun{x: x, fg: fg, bg: bg})
+		sb.WriteRune(c.Rune)
+	}
+	if len(runs) > 0 {
+		runs[len(runs)-1].text = sb.String()
+	}
+	for i := range runs {
+		runs[i].n = utf8.RuneCountInString(runs[i].text)
+	}
+	return runs
+}
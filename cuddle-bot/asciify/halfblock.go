@@ -0,0 +1,22 @@
+package asciify
+
+import (
+	"image"
+)
+
+// halfBlock is the upper half block character, whose foreground color draws the top pixel of a cell and whose background
+// color draws the bottom one.
+const halfBlock = '▀'
+
+// renderHalfBlock samples the image in color at two pixels per cell, one above the other, and draws each pair as a half block.
+// Since a character cell is roughly twice as tall as it is wide, the two pixels come out about square.
+func renderHalfBlock(m image.Image, w, h int, opts Options) *Grid {
+	colors := sampleColor(m, w, h*2, opts)
+	g := newGrid(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			g.set(x, y, Cell{Rune: halfBlock, FG: colors.at(x, y*2), BG: colors.at(x, y*2+1)})
+		}
+	}
+	return g
+}
@@ -0,0 +1,47 @@
+package asciify
+
+import (
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// WriteHTML encodes a grid as a <pre> element that can be embedded in a web page. Colored cells are wrapped in <span>s styled
+// the way they'd look in opts.Color's palette, with neighboring cells of the same color sharing a span, and uncolored cells use
+// the default text and background colors for opts.Background.
+func WriteHTML(w io.Writer, g *Grid, opts Options) error {
+	defaults := rasterColors[opts.Background]
+	mode := opts.colorMode()
+	// half blocks need each row's backgrounds to touch the next, which they don't with the usual gap between lines
+	lineHeight := 1.2
+	if opts.Mode == ModeHalfBlock {
+		lineHeight = 1
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<pre style="font-family: 'Go Mono', monospace; line-height: %g; padding: 0.5em; color: %s; background-color: %s">`,
+		lineHeight, hexColor(defaults[0]), hexColor(defaults[1]))
+	for y := 0; y < g.Height; y++ {
+		for _, run := range g.runs(y, mode, defaults) {
+			text := html.EscapeString(run.text)
+			switch {
+			case run.fg == defaults[0] && run.bg == defaults[1]:
+				sb.WriteString(text)
+			case run.bg == defaults[1]:
+				fmt.Fprintf(&sb, `<span style="color: %s">%s</span>`, hexColor(run.fg), text)
+			default:
+				fmt.Fprintf(&sb, `<span style="color: %s; background-color: %s">%s</span>`, hexColor(run.fg), hexColor(run.bg), text)
+			}
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</pre>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// hexColor formats a color as a CSS/SVG hex triplet, e.g. #2b2d31.
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,41 @@
+package asciify
+
+import (
+	"fmt"
+)
+
+// Mode selects the renderer that turns the resampled image into text.
+type Mode int
+
+const (
+	// ModeRamp draws one character per cell from a character ramp, picked by the cell's luminance.
+	ModeRamp Mode = iota
+	// ModeBraille draws the 2x4 dot patterns of the Unicode braille block, for 8 times the detail of ModeRamp in the same number
+	// of characters.
+	ModeBraille
+	// ModeHalfBlock draws two vertically stacked pixels per cell with the ▀ character, using the foreground color for the top
+	// pixel and the background color for the bottom one. It's meaningless without color, so it defaults to ColorTrue.
+	ModeHalfBlock
+	// ModeEdges draws line art, tracing the edges in the image with |, /, \, - and _ glyphs. Set Options.Blend to fill in the
+	// rest of the image from the ramp.
+	ModeEdges
+	// ModeGlyph picks the ramp character whose shape best matches each cell of the image, rather than just its brightness,
+	// keeping edges and text legible. It's much slower than ModeRamp.
+	ModeGlyph
+)
+
+var modeNames = map[string]Mode{
+	"ramp":      ModeRamp,
+	"braille":   ModeBraille,
+	"halfblock": ModeHalfBlock,
+	"edges":     ModeEdges,
+	"glyph":     ModeGlyph,
+}
+
+// ParseMode looks up a renderer by its lowercase name, e.g. "braille".
+func ParseMode(name string) (Mode, error) {
+	if mode, ok := modeNames[name]; ok {
+		return mode, nil
+	}
+	return ModeRamp, fmt.Errorf("unknown asciify mode (%s)", name)
+}
@@ -0,0 +1,113 @@
+package asciify
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"strings"
+	"unicode/utf16"
+)
+
+// mosaicCircleCoverage is how much of its square a circle emoji covers, with the display's background showing through the rest
+const mosaicCircleCoverage = 0.785
+
+// Emoji is one entry in a mosaic's palette.
+type Emoji struct {
+	// Text is what's written to show the emoji, e.g. "🟥", or "<:name:id>" for a Discord custom emoji.
+	Text string
+	// Color is the emoji's average color, as it appears on the display's background.
+	Color color.Color
+}
+
+// standardEmoji are the colored square and circle emoji, with their colors as drawn by Twemoji, which Discord uses.
+var standardEmoji = []struct {
+	square, circle string
+	c              color.RGBA
+}{
+	{"🟥", "🔴", color.RGBA{0xdd, 0x2e, 0x44, 0xff}},
+	{"🟧", "🟠", color.RGBA{0xf4, 0x90, 0x0c, 0xff}},
+	{"🟨", "🟡", color.RGBA{0xfd, 0xcb, 0x58, 0xff}},
+	{"🟩", "🟢", color.RGBA{0x78, 0xb1, 0x59, 0xff}},
+	{"🟦", "🔵", color.RGBA{0x55, 0xac, 0xee, 0xff}},
+	{"🟪", "🟣", color.RGBA{0xaa, 0x8e, 0xd6, 0xff}},
+	{"🟫", "🟤", color.RGBA{0xc1, 0x69, 0x4f, 0xff}},
+	{"⬛", "⚫", color.RGBA{0x31, 0x37, 0x3d, 0xff}},
+	{"⬜", "⚪", color.RGBA{0xe6, 0xe7, 0xe8, 0xff}},
+}
+
+// StandardEmoji is a palette of the colored square and circle emoji. Circles leave the corners of their square uncovered, so
+// they're a shade closer to the background, which gives the mosaic a few more colors to work with.
+func StandardEmoji(background Background) []Emoji {
+	bg := rasterColors[background][1]
+	palette := make([]Emoji, 0, len(standardEmoji)*2)
+	for _, e := range standardEmoji {
+		palette = append(palette, Emoji{Text: e.square, Color: e.c})
+	}
+	for _, e := range standardEmoji {
+		blend := func(c, bg uint8) uint8 {
+			return uint8(mosaicCircleCoverage*float64(c) + (1-mosaicCircleCoverage)*float64(bg) + 0.5)
+		}
+		circle := color.RGBA{blend(e.c.R, bg.R), blend(e.c.G, bg.G), blend(e.c.B, bg.B), 0xff}
+		palette = append(palette, Emoji{Text: e.circle, Color: circle})
+	}
+	return palette
+}
+
+// NewEmoji averages an emoji's image, e.g. a Discord custom emoji, to add it to a palette. Transparent pixels are blended over
+// opts.Matte if it's set, and otherwise over the default background color Rasterize uses for opts.Background, since that's
+// what shows through them.
+func NewEmoji(text string, m image.Image, opts Options) Emoji {
+	if opts.Matte == nil {
+		opts.Matte = rasterColors[opts.Background][1]
+	}
+	opts.Resample = ResampleBox
+	opts.Tone = Tone{}
+	planes := sampleColor(m, 1, 1, opts)
+	return Emoji{Text: text, Color: planes.rgba(0, 0)}
+}
+
+// Mosaic converts an image to rows of emoji, picking the one from the palette whose color is closest to each cell's average
+// color. The palette defaults to StandardEmoji, and CellAspect to 1, since emoji are square. MaxChars is counted in UTF-16
+// code units, the way Discord measures messages, since most emoji take two of them.
+func Mosaic(m image.Image, palette []Emoji, opts Options) (string, error) {
+	if opts.MaxWidth < 1 || opts.MaxHeight < 1 {
+		return "", errors.New("mosaic max size must be wider/taller than 0")
+	}
+	if len(palette) == 0 {
+		palette = StandardEmoji(opts.Background)
+	}
+	if opts.CellAspect <= 0 {
+		opts.CellAspect = 1
+	}
+	colors := make([]paletteColor, len(palette))
+	for i, e := range palette {
+		colors[i] = paletteColor{code: i, c: toRGBA(e.Color)}
+	}
+
+	src, w, h, err := layout(m.Bounds(), opts)
+	if err != nil {
+		return "", err
+	}
+	m = crop(m, src)
+	for {
+		planes := sampleColor(m, w, h, opts)
+		var sb strings.Builder
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				sb.WriteString(palette[nearestPalette(colors, planes.rgba(x, y)).code].Text)
+			}
+			sb.WriteString("\n")
+		}
+		text := sb.String()
+		if opts.MaxChars <= 0 {
+			return text, nil
+		}
+		n := len(utf16.Encode([]rune(text)))
+		if n <= opts.MaxChars {
+			return text, nil
+		}
+		if w, h, err = shrink(w, h, n, opts.MaxChars); err != nil {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package asciify
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+)
+
+const (
+	// exifHeaderLimit is how much of the start of a jpeg is kept to look for EXIF data, which has to fit in a single 64KiB APP1
+	// segment near the start of the file
+	exifHeaderLimit = 128 << 10
+
+	exifOrientationTag = 0x0112
+)
+
+// prefixWriter keeps the first limit bytes written to it and quietly drops the rest.
+type prefixWriter struct {
+	buf   []byte
+	limit int
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if room := w.limit - len(w.buf); room > 0 {
+		w.buf = append(w.buf, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// jpegOrientation finds the EXIF Orientation tag in the start of a jpeg, returning 1 (already upright) if it's missing or the
+// data is malformed.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+
+	// walk the marker segments until the image data starts
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return 1
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xda || length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the Orientation tag from the first IFD of the TIFF structure inside an EXIF segment.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for e := 0; e < entries; e++ {
+		entry := ifd + 2 + e*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			// a SHORT value is stored in the first 2 bytes of the value field
+			if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient rotates and/or flips an image according to an EXIF orientation, so it's upright the way the camera saw it.
+// Orientations 5 through 8 swap the width and height. The pixels aren't copied, they're looked up in the original image as
+// they're read, since a photo from a phone is usually big and almost always sideways.
+func orient(m image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return m
+	}
+	b := m.Bounds()
+	o := &orientedImage{m: m, orientation: orientation, w: b.Dx(), h: b.Dy()}
+	o.r = image.Rect(0, 0, o.w, o.h)
+	if orientation >= 5 {
+		o.r = image.Rect(0, 0, o.h, o.w)
+	}
+	return o
+}
+
+// orientedImage is an upright view of an image stored rotated and/or flipped.
+type orientedImage struct {
+	m           image.Image
+	orientation int
+	// w and h are the size of the stored image
+	w, h int
+	// r is the part of the upright image that's visible, which only shrinks when it's cropped
+	r image.Rectangle
+}
+
+func (o *orientedImage) ColorModel() color.Model {
+	return o.m.ColorModel()
+}
+
+func (o *orientedImage) Bounds() image.Rectangle {
+	return o.r
+}
+
+func (o *orientedImage) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(o.r)) {
+		return color.Transparent
+	}
+	sx, sy := o.source(x, y)
+	return o.m.At(sx, sy)
+}
+
+// SubImage returns a view of part of the upright image, so cropping it keeps the pixel readers' fast paths.
+func (o *orientedImage) SubImage(r image.Rectangle) image.Image {
+	sub := *o
+	sub.r = r.Intersect(o.r)
+	return &sub
+}
+
+// source maps a pixel of the upright image back to where it's stored in the original.
+func (o *orientedImage) source(x, y int) (int, int) {
+	w, h := o.w, o.h
+	var sx, sy int
+	switch o.orientation {
+	case 2: // mirrored horizontally
+		sx, sy = w-1-x, y
+	case 3: // rotated 180
+		sx, sy = w-1-x, h-1-y
+	case 4: // mirrored vertically
+		sx, sy = x, h-1-y
+	case 5: // mirrored across the main diagonal
+		sx, sy = y, x
+	case 6: // rotated 90 clockwise to be upright
+		sx, sy = y, h-1-x
+	case 7: // mirrored across the anti-diagonal
+		sx, sy = w-1-y, h-1-x
+	case 8: // rotated 90 counterclockwise to be upright
+		sx, sy = w-1-y, x
+	default:
+		sx, sy = x, y
+	}
+	min := o.m.Bounds().Min
+	return min.X + sx, min.Y + sy
+}
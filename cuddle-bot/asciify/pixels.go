@@ -0,0 +1,68 @@
+package asciify
+
+import (
+	"image"
+	"image/color"
+)
+
+// pixelRGB returns a function that reads the normalized color of the pixel at (x, y), blended over the matte. The common image
+// types are read straight from their pixel buffers, since going through At boxes every pixel in a color.Color and converts it
+// to 16 bits a channel, which dominates the time spent resampling large images.
+func pixelRGB(m image.Image, matte [3]float32) func(x, y int) (float32, float32, float32) {
+	switch m := m.(type) {
+	case *image.RGBA:
+		return func(x, y int) (float32, float32, float32) {
+			s := m.Pix[m.PixOffset(x, y):]
+			uncovered := 1 - float32(s[3])/0xff
+			return float32(s[0])/0xff + uncovered*matte[0], float32(s[1])/0xff + uncovered*matte[1], float32(s[2])/0xff + uncovered*matte[2]
+		}
+	case *image.NRGBA:
+		return func(x, y int) (float32, float32, float32) {
+			s := m.Pix[m.PixOffset(x, y):]
+			a := float32(s[3]) / 0xff
+			return (float32(s[0])*a)/0xff + (1-a)*matte[0], (float32(s[1])*a)/0xff + (1-a)*matte[1], (float32(s[2])*a)/0xff + (1-a)*matte[2]
+		}
+	case *image.Gray:
+		return func(x, y int) (float32, float32, float32) {
+			v := float32(m.Pix[m.PixOffset(x, y)]) / 0xff
+			return v, v, v
+		}
+	case *image.YCbCr:
+		return func(x, y int) (float32, float32, float32) {
+			r, g, b := color.YCbCrToRGB(m.Y[m.YOffset(x, y)], m.Cb[m.COffset(x, y)], m.Cr[m.COffset(x, y)])
+			return float32(r) / 0xff, float32(g) / 0xff, float32(b) / 0xff
+		}
+	case *orientedImage:
+		rgb := pixelRGB(m.m, matte)
+		return func(x, y int) (float32, float32, float32) {
+			return rgb(m.source(x, y))
+		}
+	}
+	return func(x, y int) (float32, float32, float32) {
+		return flatten(m.At(x, y), matte)
+	}
+}
+
+// pixelGray returns a function that reads the normalized luminance of the pixel at (x, y), blended over the matte, reading
+// gray and YCbCr images' luma directly instead of converting to RGB and back.
+func pixelGray(m image.Image, matte [3]float32) func(x, y int) float32 {
+	switch m := m.(type) {
+	case *image.Gray:
+		return func(x, y int) float32 {
+			return float32(m.Pix[m.PixOffset(x, y)]) / 0xff
+		}
+	case *image.YCbCr:
+		return func(x, y int) float32 {
+			return float32(m.Y[m.YOffset(x, y)]) / 0xff
+		}
+	case *orientedImage:
+		gray := pixelGray(m.m, matte)
+		return func(x, y int) float32 {
+			return gray(m.source(x, y))
+		}
+	}
+	rgb := pixelRGB(m, matte)
+	return func(x, y int) float32 {
+		return luminance(rgb(x, y))
+	}
+}
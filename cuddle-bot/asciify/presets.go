@@ -0,0 +1,46 @@
+package asciify
+
+import (
+	"fmt"
+)
+
+// Calibrated ramps, generated with cmd/calibrate from the printable ASCII characters (CalibrationChars), so they're ordered by
+// how much ink each glyph actually covers instead of by eye.
+//
+// Discord draws code blocks in its own gg mono font, which isn't freely available, so the Discord ramps are measured with
+// DejaVu Sans Mono, a fallback from the same code block font stack:
+//
+//	go run ./cmd/calibrate -font DejaVuSansMono.ttf -n 70
+//	go run ./cmd/calibrate -font DejaVuSansMono.ttf -n 16
+//
+// The Go Mono ramps are measured with the font Rasterize draws with by default:
+//
+//	go run ./cmd/calibrate -n 70
+//	go run ./cmd/calibrate -n 16
+const (
+	RampDiscord      = "@BWQR80D#HOK&EUGP$X5Zawe2oyCn1Y{sxf7Ljztv[]icl?=|><)(+\\/r*!^;\"~:,'-_. "
+	RampDiscordShort = "@QHEXeCxc\\*^:'. "
+	RampGoMono       = "MBH&RQ0WD8Kp@$h%PX#ASZ3nTyLxoCsjJzI1rvtil{[]}c7?()*\\><=/;+|!^\":~_,-'. "
+	RampGoMonoShort  = "MB8b#njr)*^:-'. "
+)
+
+// RampBlocks uses the shade block characters, which are already evenly spaced in most fonts.
+const RampBlocks = "█▓▒░ "
+
+// Presets are the named ramps, for picking one by name instead of spelling it out.
+var Presets = map[string]string{
+	"default":   DefaultRamp,
+	"discord":   RampDiscord,
+	"discord16": RampDiscordShort,
+	"gomono":    RampGoMono,
+	"gomono16":  RampGoMonoShort,
+	"blocks":    RampBlocks,
+}
+
+// ParsePreset looks up a preset ramp by its lowercase name, e.g. "discord".
+func ParsePreset(name string) (string, error) {
+	if ramp, ok := Presets[name]; ok {
+		return ramp, nil
+	}
+	return "", fmt.Errorf("unknown ramp preset (%s)", name)
+}
@@ -0,0 +1,74 @@
+package asciify
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// DefaultRamp is the character ramp used when Options.Ramp is empty, ordered from the glyph drawn for the darkest pixels to
+// the glyph drawn for the lightest pixels when the text sits on a light background.
+const DefaultRamp = "$@B%8&WM#*oahkbdpqwmZO0QLCJUYXzcvunxrjft/\\|()1{}[]?-_+~<>i!lI;:,\"^`'. "
+
+// Background describes the color behind the rendered text. Dense glyphs look dark on a light background but bright on a dark
+// background, so the ramp has to be flipped to keep the picture from looking like a negative.
+type Background int
+
+const (
+	// BackgroundLight is for dark text on a light background, e.g. a terminal or editor with a light theme.
+	BackgroundLight Background = iota
+	// BackgroundDark is for light text on a dark background, e.g. Discord's default dark theme.
+	BackgroundDark
+)
+
+var backgroundNames = map[string]Background{
+	"light": BackgroundLight,
+	"dark":  BackgroundDark,
+}
+
+// ParseBackground looks up a background by its lowercase name, e.g. "dark".
+func ParseBackground(name string) (Background, error) {
+	if background, ok := backgroundNames[name]; ok {
+		return background, nil
+	}
+	return BackgroundLight, fmt.Errorf("unknown background (%s)", name)
+}
+
+// ramp is a resolved character ramp, ordered so that index 0 is drawn for the darkest pixels.
+type ramp []rune
+
+// newRamp resolves the ramp described by the options, decoding multi-byte runes and flipping it for a dark background and/or
+// inversion.
+func newRamp(opts Options) (ramp, error) {
+	chars := opts.Ramp
+	if chars == "" {
+		chars = DefaultRamp
+	}
+	if !utf8.ValidString(chars) {
+		return nil, errors.New("ascii ramp must be valid utf-8")
+	}
+	r := ramp(chars)
+	if len(r) < 2 {
+		return nil, errors.New("ascii ramp must have at least 2 characters")
+	}
+
+	// a dark background and an explicit inversion cancel each other out
+	if (opts.Background == BackgroundDark) != opts.Invert {
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+	}
+	return r, nil
+}
+
+// glyph picks the character for a luminance value in [0, 1].
+func (r ramp) glyph(lum float32) rune {
+	return r[r.index(lum)]
+}
+
+// index maps a luminance value in [0, 1] to the nearest of the ramp's evenly spaced levels, clamping anything out of range.
+// Rounding to the nearest level (rather than truncating) means values already quantized by dither map back onto exactly the
+// level they were quantized to.
+func (r ramp) index(lum float32) int {
+	return int(clamp01(lum)*float32(len(r)-1) + 0.5)
+}
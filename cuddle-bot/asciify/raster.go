@@ -0,0 +1,114 @@
+package asciify
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// rasterFontSize is the size in pixels of the default face used by Rasterize
+const rasterFontSize = 14
+
+var (
+	// the default text and background colors for each Background, borrowed from Discord's code blocks
+	rasterColors = map[Background][2]color.RGBA{
+		BackgroundLight: {{0x2e, 0x33, 0x38, 0xff}, {0xf2, 0xf3, 0xf5, 0xff}},
+		BackgroundDark:  {{0xdb, 0xde, 0xe1, 0xff}, {0x2b, 0x2d, 0x31, 0xff}},
+	}
+
+	defaultFont     *opentype.Font
+	defaultFontErr  error
+	defaultFontOnce sync.Once
+)
+
+// Rasterize draws a grid as an image with a monospace font, e.g. so wide output can be viewed on a phone without wrapping.
+// opts.Face picks the font, defaulting to Go Mono, and cell colors are drawn the way they'd look in opts.Color's palette.
+// Uncolored cells use the default text and background colors for opts.Background.
+func Rasterize(g *Grid, opts Options) (*image.RGBA, error) {
+	face, err := opts.face()
+	if err != nil {
+		return nil, err
+	}
+
+	// every glyph in a monospace font has the same advance, so any one will do for the cell width
+	metrics := face.Metrics()
+	advance, _ := face.GlyphAdvance('0')
+	cellWidth, cellHeight := advance.Ceil(), metrics.Height.Ceil()
+	ascent := metrics.Ascent.Ceil()
+
+	defaults := rasterColors[opts.Background]
+	colorMode := opts.colorMode()
+	dst := image.NewRGBA(image.Rect(0, 0, g.Width*cellWidth, g.Height*cellHeight))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(defaults[1]), image.Point{}, draw.Src)
+	drawer := &font.Drawer{Dst: dst, Face: face}
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			c := g.At(x, y)
+			cell := image.Rect(x*cellWidth, y*cellHeight, (x+1)*cellWidth, (y+1)*cellHeight)
+			fg, bg := colorMode.display(c.FG, false, defaults[0]), colorMode.display(c.BG, true, defaults[1])
+			draw.Draw(dst, cell, image.NewUniform(bg), image.Point{}, draw.Src)
+
+			// some glyphs are drawn by hand, since they need to meet their neighbors exactly or aren't in most fonts
+			switch {
+			case c.Rune == halfBlock:
+				top := image.Rect(cell.Min.X, cell.Min.Y, cell.Max.X, cell.Min.Y+cellHeight/2)
+				draw.Draw(dst, top, image.NewUniform(fg), image.Point{}, draw.Src)
+			case c.Rune > brailleBlank && c.Rune <= brailleBlank+0xff:
+				drawBraille(dst, cell, c.Rune, fg)
+			case c.Rune != ' ' && c.Rune != brailleBlank:
+				drawer.Src = image.NewUniform(fg)
+				drawer.Dot = fixed.P(cell.Min.X, cell.Min.Y+ascent)
+				drawer.DrawString(string(c.Rune))
+			}
+		}
+	}
+	return dst, nil
+}
+
+// face returns the font to draw characters with. The default Go Mono font is only parsed once, but each call gets its own
+// face, since faces aren't safe to share between goroutines.
+func (opts Options) face() (font.Face, error) {
+	if opts.Face != nil {
+		return opts.Face, nil
+	}
+	defaultFontOnce.Do(func() {
+		defaultFont, defaultFontErr = opentype.Parse(gomono.TTF)
+	})
+	if defaultFontErr != nil {
+		return nil, defaultFontErr
+	}
+	return opentype.NewFace(defaultFont, &opentype.FaceOptions{Size: rasterFontSize, DPI: 72, Hinting: font.HintingFull})
+}
+
+// WritePNG rasterizes a grid with Rasterize and encodes it as a PNG.
+func WritePNG(w io.Writer, g *Grid, opts Options) error {
+	m, err := Rasterize(g, opts)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, m)
+}
+
+// drawBraille draws the raised dots of a braille character as squares on a 2x4 grid within the cell.
+func drawBraille(dst draw.Image, cell image.Rectangle, r rune, c color.Color) {
+	dotWidth, dotHeight := cell.Dx()/brailleCellWidth, cell.Dy()/brailleCellHeight
+	size := max(min(dotWidth, dotHeight)*2/3, 1)
+	for dy, row := range brailleDots {
+		for dx, bit := range row {
+			if r&bit == 0 {
+				continue
+			}
+			x := cell.Min.X + dx*dotWidth + (dotWidth-size)/2
+			y := cell.Min.Y + dy*dotHeight + (dotHeight-size)/2
+			draw.Draw(dst, image.Rect(x, y, x+size, y+size), image.NewUniform(c), image.Point{}, draw.Src)
+		}
+	}
+}
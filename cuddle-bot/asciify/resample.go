@@ -0,0 +1,235 @@
+package asciify
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sync"
+)
+
+// Resample selects how source pixels are combined into each output sample.
+type Resample int
+
+const (
+	// ResampleNearest picks the single source pixel at the top left of each output sample. It's the fastest, but large images
+	// turn into aliased noise and thin lines can disappear entirely.
+	ResampleNearest Resample = iota
+	// ResampleBox averages every source pixel covered by an output sample, weighted by how much of each pixel is covered.
+	ResampleBox
+	// ResampleBilinear filters with a triangle kernel stretched to the downscaling factor, which is a little softer than box.
+	ResampleBilinear
+	// ResampleLanczos filters with a 3-lobed Lanczos kernel stretched to the downscaling factor, which keeps edges sharper.
+	ResampleLanczos
+)
+
+var resampleNames = map[string]Resample{
+	"nearest":  ResampleNearest,
+	"box":      ResampleBox,
+	"bilinear": ResampleBilinear,
+	"lanczos":  ResampleLanczos,
+}
+
+// ParseResample looks up a resampling mode by its lowercase name, e.g. "box".
+func ParseResample(name string) (Resample, error) {
+	if mode, ok := resampleNames[name]; ok {
+		return mode, nil
+	}
+	return ResampleNearest, fmt.Errorf("unknown resampling mode (%s)", name)
+}
+
+// plane is a grid of samples normalized to [0, 1], stored row by row.
+type plane struct {
+	w, h int
+	pix  []float32
+}
+
+func newPlane(w, h int) plane {
+	return plane{w: w, h: h, pix: make([]float32, w*h)}
+}
+
+func (p plane) at(x, y int) float32 {
+	return p.pix[y*p.w+x]
+}
+
+func (p plane) set(x, y int, v float32) {
+	p.pix[y*p.w+x] = v
+}
+
+// weight is the contribution of one source row or column to an output sample.
+type weight struct {
+	i int
+	w float32
+}
+
+// sampleGray resamples the luminance of m down (or up) to a w x h plane, and applies any tone adjustments. Pixels that aren't
+// opaque are blended over the matte first.
+func sampleGray(m image.Image, w, h int, opts Options) plane {
+	p := resample(m.Bounds(), w, h, opts.Resample, opts.Workers, pixelGray(m, opts.matte()))
+	opts.Tone.apply(p)
+	return p
+}
+
+// resample is a separable filter: each output sample is a weighted sum over source columns, then over source rows. get reads
+// a single normalized source value in the bounds' coordinate space, and must be safe to call from multiple goroutines when
+// workers is more than 1.
+func resample(bounds image.Rectangle, w, h int, mode Resample, workers int, get func(x, y int) float32) plane {
+	cols := filterWeights(bounds.Dx(), w, mode)
+	rows := filterWeights(bounds.Dy(), h, mode)
+
+	// only filter the source rows that some output row actually uses, since nearest sampling only touches a handful of them
+	used := make([]bool, bounds.Dy())
+	var sources []int
+	for _, ws := range rows {
+		for _, rw := range ws {
+			if !used[rw.i] {
+				used[rw.i] = true
+				sources = append(sources, rw.i)
+			}
+		}
+	}
+	filtered := make([][]float32, bounds.Dy())
+	buf := make([]float32, len(sources)*w)
+	for i, sy := range sources {
+		filtered[sy] = buf[i*w : (i+1)*w]
+	}
+	parallel(len(sources), workers, func(lo, hi int) {
+		for _, sy := range sources[lo:hi] {
+			r := filtered[sy]
+			for x, ws := range cols {
+				var sum float32
+				for _, cw := range ws {
+					sum += cw.w * get(bounds.Min.X+cw.i, bounds.Min.Y+sy)
+				}
+				r[x] = sum
+			}
+		}
+	})
+
+	p := newPlane(w, h)
+	parallel(h, workers, func(lo, hi int) {
+		for y := lo; y < hi; y++ {
+			out := p.pix[y*w : (y+1)*w]
+			for _, rw := range rows[y] {
+				for x, v := range filtered[rw.i] {
+					out[x] += rw.w * v
+				}
+			}
+			// lanczos lobes can overshoot
+			for x, v := range out {
+				out[x] = clamp01(v)
+			}
+		}
+	})
+	return p
+}
+
+// parallel splits [0, n) into contiguous chunks and calls fn on each from its own goroutine, or just calls fn(0, n) when
+// there's only one worker.
+func parallel(n, workers int, fn func(lo, hi int)) {
+	workers = min(workers, n)
+	if workers < 2 {
+		fn(0, n)
+		return
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			fn(lo, hi)
+		}(n*i/workers, n*(i+1)/workers)
+	}
+	wg.Wait()
+}
+
+// filterWeights computes, for each of dstLen output samples, which of srcLen source samples contribute and by how much.
+func filterWeights(srcLen, dstLen int, mode Resample) [][]weight {
+	scale := float64(srcLen) / float64(dstLen)
+	weights := make([][]weight, dstLen)
+	for i := range weights {
+		switch mode {
+		case ResampleBox:
+			weights[i] = boxWeights(srcLen, float64(i)*scale, float64(i+1)*scale)
+		case ResampleBilinear:
+			weights[i] = kernelWeights(srcLen, i, scale, 1, triangle)
+		case ResampleLanczos:
+			weights[i] = kernelWeights(srcLen, i, scale, 3, lanczos3)
+		default:
+			weights[i] = []weight{{i: min(int(float64(i)*scale), srcLen-1), w: 1}}
+		}
+	}
+	return weights
+}
+
+// boxWeights covers the source span [start, end) exactly, so partially covered pixels count for their covered fraction.
+func boxWeights(srcLen int, start, end float64) []weight {
+	var ws []weight
+	total := 0.0
+	for j := int(start); j < srcLen && float64(j) < end; j++ {
+		overlap := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+		if overlap <= 0 {
+			continue
+		}
+		ws = append(ws, weight{i: j, w: float32(overlap)})
+		total += overlap
+	}
+	return normalize(ws, total)
+}
+
+// kernelWeights centers a kernel on output sample i, stretching it by the scale when downsampling so it acts as a low-pass
+// filter instead of skipping over source pixels.
+func kernelWeights(srcLen, i int, scale, radius float64, kernel func(float64) float64) []weight {
+	stretch := math.Max(scale, 1)
+	center := (float64(i)+0.5)*scale - 0.5
+	support := radius * stretch
+	var ws []weight
+	total := 0.0
+	for j := int(math.Ceil(center - support)); float64(j) <= center+support; j++ {
+		k := kernel((float64(j) - center) / stretch)
+		if k == 0 {
+			continue
+		}
+		// clamp to the edge rather than dropping out-of-range taps, which would darken the borders
+		ws = append(ws, weight{i: min(max(j, 0), srcLen-1), w: float32(k)})
+		total += k
+	}
+	return normalize(ws, total)
+}
+
+func normalize(ws []weight, total float64) []weight {
+	if total == 0 {
+		return ws
+	}
+	for i := range ws {
+		ws[i].w = float32(float64(ws[i].w) / total)
+	}
+	return ws
+}
+
+func triangle(x float64) float64 {
+	x = math.Abs(x)
+	if x >= 1 {
+		return 0
+	}
+	return 1 - x
+}
+
+func lanczos3(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	if x <= -3 || x >= 3 {
+		return 0
+	}
+	px := math.Pi * x
+	return 3 * math.Sin(px) * math.Sin(px/3) / (px * px)
+}
+
+func clamp01(v float32) float32 {
+	if v < 0 {
+		return 0
+	} else if v > 1 {
+		return 1
+	}
+	return v
+}
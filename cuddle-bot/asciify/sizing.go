@@ -0,0 +1,114 @@
+package asciify
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+// DefaultCellAspect is the width of a character cell divided by its height in most monospace fonts, used when
+// Options.CellAspect isn't set.
+const DefaultCellAspect = 0.5
+
+// Fit selects how the image is sized to the MaxWidth x MaxHeight box of cells.
+type Fit int
+
+const (
+	// FitContain scales the image to fit entirely inside the box, keeping its aspect ratio, so the output may be narrower or
+	// shorter than the box.
+	FitContain Fit = iota
+	// FitFill scales the image to cover the whole box, keeping its aspect ratio, and crops whatever hangs over the edges
+	// equally from both sides.
+	FitFill
+	// FitStretch scales the image to exactly the box, distorting it if the aspect ratios don't match.
+	FitStretch
+)
+
+var fitNames = map[string]Fit{
+	"fit":     FitContain,
+	"fill":    FitFill,
+	"stretch": FitStretch,
+}
+
+// ParseFit looks up a sizing mode by its lowercase name, e.g. "fill".
+func ParseFit(name string) (Fit, error) {
+	if fit, ok := fitNames[name]; ok {
+		return fit, nil
+	}
+	return FitContain, fmt.Errorf("unknown fit mode (%s)", name)
+}
+
+// layout works out which part of the image to sample, after any explicit crop and any cropping to fill the box, and how many
+// cells wide and tall the output will be.
+func layout(bounds image.Rectangle, opts Options) (image.Rectangle, int, int, error) {
+	src := bounds
+	if !opts.Crop.Empty() {
+		src = opts.Crop.Add(bounds.Min).Intersect(bounds)
+		if src.Empty() {
+			return src, 0, 0, errors.New("crop must overlap the image")
+		}
+	}
+	if src.Dx() < 1 || src.Dy() < 1 {
+		return src, 0, 0, errors.New("input image size must be wider/taller than 0")
+	}
+
+	aspect := opts.CellAspect
+	if aspect <= 0 {
+		aspect = DefaultCellAspect
+	}
+	boxWidth, boxHeight := float64(opts.MaxWidth), float64(opts.MaxHeight)
+	inWidth, inHeight := float64(src.Dx()), float64(src.Dy())
+	// an image drawn w cells wide comes out w * aspect cells tall (in units of cell height) if it's square
+	imageRows := func(cols float64) float64 { return cols * aspect * inHeight / inWidth }
+	imageCols := func(rows float64) float64 { return rows / aspect * inWidth / inHeight }
+
+	w, h := boxWidth, boxHeight
+	switch opts.Fit {
+	case FitFill:
+		// crop whichever dimension of the source is too long for the box's shape, keeping the middle
+		boxRatio := boxWidth * aspect / boxHeight
+		if inWidth/inHeight > boxRatio {
+			cropWidth := int(math.Round(inHeight * boxRatio))
+			src.Min.X += (src.Dx() - cropWidth) / 2
+			src.Max.X = src.Min.X + max(cropWidth, 1)
+		} else {
+			cropHeight := int(math.Round(inWidth / boxRatio))
+			src.Min.Y += (src.Dy() - cropHeight) / 2
+			src.Max.Y = src.Min.Y + max(cropHeight, 1)
+		}
+	case FitStretch:
+	default:
+		h = imageRows(boxWidth)
+		if h > boxHeight {
+			w, h = imageCols(boxHeight), boxHeight
+		}
+	}
+
+	// very wide or tall images round down to nothing across the short side, so keep at least one cell either way
+	cols, rows := max(int(math.Round(w)), 1), max(int(math.Round(h)), 1)
+	return src, cols, rows, nil
+}
+
+// crop narrows an image down to a rectangle, without copying it if the image type supports sub-images.
+func crop(m image.Image, r image.Rectangle) image.Image {
+	if r == m.Bounds() {
+		return m
+	}
+	if sub, ok := m.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(r)
+	}
+	return croppedImage{m, r}
+}
+
+// croppedImage is a view of part of an image that has no SubImage method.
+type croppedImage struct {
+	image.Image
+	r image.Rectangle
+}
+
+func (c croppedImage) Bounds() image.Rectangle {
+	return c.r
+}
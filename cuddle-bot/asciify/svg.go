@@ -0,0 +1,65 @@
+package asciify
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+const (
+	// svgFontSize is the size in pixels text is drawn at by WriteSVG
+	svgFontSize = 14
+	// svgCellWidth and svgCellHeight are the size of a character cell in ems, which suits most monospace fonts
+	svgCellWidth, svgCellHeight = 0.6, 1.2
+)
+
+// WriteSVG encodes a grid as an SVG image, with one line of text per row on top of rectangles for any background colors. The
+// viewer's monospace font is used, and each row is stretched to exactly the width of the grid, so the columns line up however
+// wide its characters are. Cell colors are drawn the way they'd look in opts.Color's palette, and uncolored cells use the
+// default text and background colors for opts.Background.
+func WriteSVG(w io.Writer, g *Grid, opts Options) error {
+	defaults := rasterColors[opts.Background]
+	mode := opts.colorMode()
+	cellWidth, cellHeight := svgFontSize*svgCellWidth, svgFontSize*svgCellHeight
+	width, height := float64(g.Width)*cellWidth, float64(g.Height)*cellHeight
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%.6g" height="%.6g" viewBox="0 0 %.6g %.6g">`+"\n", width, height, width, height)
+	fmt.Fprintf(&sb, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hexColor(defaults[1]))
+
+	// backgrounds go underneath all the text, so characters that poke out of their cells aren't covered up by the next row
+	rows := make([][]colorRun, g.Height)
+	for y := range rows {
+		rows[y] = g.runs(y, mode, defaults)
+		for _, run := range rows[y] {
+			x, w := float64(run.x)*cellWidth, float64(run.n)*cellWidth
+			if run.bg != defaults[1] {
+				fmt.Fprintf(&sb, `<rect x="%.6g" y="%.6g" width="%.6g" height="%.6g" fill="%s"/>`+"\n", x, float64(y)*cellHeight, w, cellHeight, hexColor(run.bg))
+			}
+			// half blocks are drawn by hand, the same as Rasterize does, so they meet their neighbors exactly
+			if strings.Trim(run.text, string(halfBlock)) == "" {
+				fmt.Fprintf(&sb, `<rect x="%.6g" y="%.6g" width="%.6g" height="%.6g" fill="%s"/>`+"\n", x, float64(y)*cellHeight, w, cellHeight/2, hexColor(run.fg))
+			}
+		}
+	}
+
+	// the baseline sits where it would if the ascent and descent split the line height 4:1, like they do in most fonts
+	fmt.Fprintf(&sb, `<g font-family="'Go Mono', monospace" font-size="%d" fill="%s" style="white-space: pre" xml:space="preserve">`+"\n",
+		svgFontSize, hexColor(defaults[0]))
+	for y, runs := range rows {
+		fmt.Fprintf(&sb, `<text x="0" y="%.6g" textLength="%.6g" lengthAdjust="spacing">`, (float64(y)+0.8)*cellHeight, width)
+		for _, run := range runs {
+			text := html.EscapeString(strings.ReplaceAll(run.text, string(halfBlock), " "))
+			if run.fg == defaults[0] {
+				sb.WriteString(text)
+			} else {
+				fmt.Fprintf(&sb, `<tspan fill="%s">%s</tspan>`, hexColor(run.fg), text)
+			}
+		}
+		sb.WriteString("</text>\n")
+	}
+	sb.WriteString("</g>\n</svg>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
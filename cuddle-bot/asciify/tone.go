@@ -0,0 +1,166 @@
+package asciify
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	// autoLevelsClip is the fraction of samples at each end of the histogram that AutoLevels ignores, so a few stray specks
+	// of pure black or white don't stop the rest of the image from being stretched
+	autoLevelsClip = 0.01
+
+	// claheTiles is how many tiles across and down Equalize splits the image into, claheBins is the number of histogram bins
+	// per tile, and claheClip is how many times the average bin count a bin can hold before the excess is spread over the
+	// others, which limits how much noise in flat areas gets amplified
+	claheTiles = 4
+	claheBins  = 64
+	claheClip  = 2.5
+)
+
+// Tone adjusts luminance before characters are picked, so dark or washed-out images use more of the ramp. The zero value leaves
+// the image alone.
+type Tone struct {
+	// Brightness is added to every sample, from -1 (black) to 1 (white).
+	Brightness float32
+	// Contrast scales every sample's distance from middle gray, e.g. 1.4 for 40% more contrast. Zero means no change.
+	Contrast float32
+	// Gamma raises every sample to the power 1/Gamma, so values above 1 brighten the midtones and values below 1 darken them.
+	// Zero means no change.
+	Gamma float32
+	// AutoLevels stretches the image's luminance to cover the full range from black to white. Like Equalize, it only applies
+	// to the luminance characters are picked by, so it doesn't change colors, or anything in ModeHalfBlock or Mosaic.
+	AutoLevels bool
+	// Equalize applies contrast limited adaptive histogram equalization (CLAHE), which spreads out the luminance within each
+	// region of the image, bringing out detail in both shadows and highlights.
+	Equalize bool
+}
+
+// isZero reports whether the tone leaves every sample unchanged.
+func (t Tone) isZero() bool {
+	return t == Tone{}
+}
+
+// apply adjusts a luminance plane in place. The histogram based adjustments happen first, so the manual ones are relative to
+// an already balanced image.
+func (t Tone) apply(p plane) {
+	if t.isZero() {
+		return
+	}
+	if t.AutoLevels {
+		autoLevels(p)
+	}
+	if t.Equalize {
+		equalize(p)
+	}
+	t.applyPointwise(p)
+}
+
+// applyPointwise applies only the adjustments that don't depend on the rest of the image, e.g. to each channel of a color
+// plane.
+func (t Tone) applyPointwise(p plane) {
+	if t.Brightness == 0 && (t.Contrast == 0 || t.Contrast == 1) && (t.Gamma == 0 || t.Gamma == 1) {
+		return
+	}
+	for i, v := range p.pix {
+		p.pix[i] = t.adjust(v)
+	}
+}
+
+// adjust applies brightness, contrast and gamma to a single sample.
+func (t Tone) adjust(v float32) float32 {
+	v += t.Brightness
+	if t.Contrast != 0 {
+		v = (v-0.5)*t.Contrast + 0.5
+	}
+	v = clamp01(v)
+	if t.Gamma > 0 && t.Gamma != 1 {
+		v = float32(math.Pow(float64(v), 1/float64(t.Gamma)))
+	}
+	return v
+}
+
+// autoLevels linearly stretches the plane so its darkest and lightest samples, give or take autoLevelsClip, become black and
+// white.
+func autoLevels(p plane) {
+	if len(p.pix) == 0 {
+		return
+	}
+	sorted := slices.Clone(p.pix)
+	slices.Sort(sorted)
+	clip := int(float64(len(sorted)) * autoLevelsClip)
+	lo, hi := sorted[clip], sorted[len(sorted)-1-clip]
+	if hi-lo < 1.0/255 {
+		return
+	}
+	for i, v := range p.pix {
+		p.pix[i] = clamp01((v - lo) / (hi - lo))
+	}
+}
+
+// equalize applies CLAHE: each tile gets its own clipped histogram equalization mapping, and each sample is mapped by blending
+// the mappings of the 4 nearest tile centers, so there are no seams between tiles.
+func equalize(p plane) {
+	tilesX, tilesY := min(claheTiles, p.w), min(claheTiles, p.h)
+	if tilesX < 1 || tilesY < 1 {
+		return
+	}
+	tileWidth, tileHeight := float64(p.w)/float64(tilesX), float64(p.h)/float64(tilesY)
+	bin := func(v float32) int {
+		return min(int(clamp01(v)*claheBins), claheBins-1)
+	}
+
+	// build each tile's mapping from bin to equalized value
+	mappings := make([][claheBins]float32, tilesX*tilesY)
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			var hist [claheBins]float64
+			x0, x1 := int(float64(tx)*tileWidth), int(float64(tx+1)*tileWidth)
+			y0, y1 := int(float64(ty)*tileHeight), int(float64(ty+1)*tileHeight)
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					hist[bin(p.at(x, y))]++
+				}
+			}
+			n := float64((x1 - x0) * (y1 - y0))
+			if n == 0 {
+				continue
+			}
+
+			// clip the tall bins and hand the excess out evenly
+			limit := math.Max(claheClip*n/claheBins, 1)
+			var excess float64
+			for i, c := range hist {
+				if c > limit {
+					excess += c - limit
+					hist[i] = limit
+				}
+			}
+			var cdf float64
+			for i, c := range hist {
+				cdf += c + excess/claheBins
+				mappings[ty*tilesX+tx][i] = float32(cdf / n)
+			}
+		}
+	}
+
+	// interpolate between the mappings of the surrounding tile centers
+	mapping := func(tx, ty, b int) float32 {
+		tx, ty = min(max(tx, 0), tilesX-1), min(max(ty, 0), tilesY-1)
+		return mappings[ty*tilesX+tx][b]
+	}
+	for y := 0; y < p.h; y++ {
+		fy := (float64(y)+0.5)/tileHeight - 0.5
+		ty := int(math.Floor(fy))
+		wy := float32(fy - float64(ty))
+		for x := 0; x < p.w; x++ {
+			fx := (float64(x)+0.5)/tileWidth - 0.5
+			tx := int(math.Floor(fx))
+			wx := float32(fx - float64(tx))
+			b := bin(p.at(x, y))
+			top := mapping(tx, ty, b)*(1-wx) + mapping(tx+1, ty, b)*wx
+			bottom := mapping(tx, ty+1, b)*(1-wx) + mapping(tx+1, ty+1, b)*wx
+			p.set(x, y, top*(1-wy)+bottom*wy)
+		}
+	}
+}
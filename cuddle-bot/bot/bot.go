@@ -2,17 +2,17 @@ package bot
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
 	"slices"
-	"strconv"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/google/uuid"
 
 	"github.com/cmmonosmith/cuddle-bot/asciify"
 )
@@ -23,6 +23,14 @@ type bot struct {
 	id   string
 	s    *discordgo.Session
 	m    *messenger
+	// commands are everything the bot can be asked to do, by mention or slash command
+	commands *registry
+
+	// emoji holds each server's custom emoji palette for mosaics, keyed by guild ID, and emojiGen counts how many times each
+	// one has started being precomputed, so a palette that finishes late doesn't replace a newer one
+	emoji    map[string][]asciify.Emoji
+	emojiGen map[string]uint64
+	emojiMu  sync.RWMutex
 }
 
 const (
@@ -30,26 +38,88 @@ const (
 	cmdHi        = "hi"
 	cmdAsciify   = "asciify"
 	cmdAsciifile = "asciifile"
+	cmdAsciimage = "asciimage"
+	cmdBanner    = "banner"
+	cmdMosaic    = "mosaic"
 )
 
-var (
-	//TODO: move commands, arguments, and help docs to config
-	cmdHelps = map[string]string{
-		cmdHelp:      "print this help text, or print more detailed help text for a specific command",
-		cmdHi:        "respond to your casual greeting",
-		cmdAsciify:   "convert a PNG or JPEG image to ascii directly in the response",
-		cmdAsciifile: "convert a PNG or JPEG image to ascii and attach it to the response as a TXT file",
-	}
-	asciifyAttachmentTypes = []string{"image/png", "image/jpeg"}
-)
+// newCommands builds the registry of everything the bot can do, in the order the slash command lists them.
+// TODO: move commands, arguments, and help docs to config
+func newCommands() *registry {
+	reg := &registry{}
+	reg.register(
+		&command{
+			name:        cmdHelp,
+			description: "print this help text, or print more detailed help text for a specific command",
+			args:        helpArgs,
+			help:        Help{Examples: []string{"", cmdAsciify}},
+			handle:      (*bot).help,
+		},
+		&command{
+			name:        cmdHi,
+			description: "respond to your casual greeting",
+			help:        Help{Aliases: []string{"hello", "hey"}, Examples: []string{""}},
+			handle: func(b *bot, r *request) {
+				r.reply("sup sup :sunglasses:")
+			},
+		},
+		&command{
+			name:        cmdAsciify,
+			description: "convert an image to ascii directly in the response, animating GIFs",
+			args:        slices.Concat(imageArgs(asciifyMaxWidth, asciifyMaxHeight), renderArgs(inlinePresets)),
+			help:        Help{Aliases: []string{"ascii"}, Examples: []string{"40 20", "mode=braille invert", "color mode=halfblock"}},
+			handle: func(b *bot, r *request) {
+				b.asciify(r, outputInline)
+			},
+		},
+		&command{
+			name:        cmdAsciifile,
+			description: "convert an image to ascii and attach it to the response as a TXT, HTML, or SVG file",
+			args:        slices.Concat(imageArgs(asciifileMaxWidth, asciifileMaxHeight), renderArgs(filePresets), asciifileArgs),
+			help:        Help{Examples: []string{"format=html color=truecolor", "200 100 mode=glyph"}},
+			handle: func(b *bot, r *request) {
+				b.asciify(r, outputFile)
+			},
+		},
+		&command{
+			name:        cmdAsciimage,
+			description: "convert an image to ascii and attach it to the response drawn as a PNG",
+			args:        slices.Concat(imageArgs(asciifileMaxWidth, asciifileMaxHeight), renderArgs(filePresets)),
+			help:        Help{Examples: []string{"128 64 color=truecolor"}},
+			handle: func(b *bot, r *request) {
+				b.asciify(r, outputImage)
+			},
+		},
+		&command{
+			name:        cmdBanner,
+			description: "draw some text as a big ascii banner in a FIGlet font",
+			args:        bannerArgs,
+			help:        Help{Aliases: []string{"figlet"}, Examples: []string{"standard hello world", "braille \"big news\""}},
+			handle:      (*bot).banner,
+		},
+		&command{
+			name:        cmdMosaic,
+			description: "convert an image to a mosaic of colored emoji directly in the response",
+			args:        mosaicArgs,
+			help:        Help{Aliases: []string{"emojify"}, Examples: []string{"custom", "10 10 fit=fill"}},
+			handle: func(b *bot, r *request) {
+				b.asciify(r, outputMosaic)
+			},
+		},
+	)
+	return reg
+}
 
 // New constructs a bot instance with the name from the host environment and the user ID from an active session.
 func newBot(session *discordgo.Session, messenger *messenger) *bot {
 	return &bot{
-		name: session.State.User.Username,
-		id:   session.State.User.ID,
-		s:    session,
-		m:    messenger,
+		name:     session.State.User.Username,
+		id:       session.State.User.ID,
+		s:        session,
+		m:        messenger,
+		commands: newCommands(),
+		emoji:    map[string][]asciify.Emoji{},
+		emojiGen: map[string]uint64{},
 	}
 }
 
@@ -64,8 +134,8 @@ func (b *bot) newMessage(message *discordgo.MessageCreate) {
 	}
 
 	// respond to user message if it starts with an @me
-	parts := strings.Split(message.Content, " ")
-	if parts[0] != fmt.Sprintf("<@%s>", b.id) {
+	parts := splitArgs(message.Content)
+	if len(parts) == 0 || parts[0] != fmt.Sprintf("<@%s>", b.id) {
 		return
 	}
 
@@ -81,142 +151,172 @@ func (b *bot) newMessage(message *discordgo.MessageCreate) {
 		b.m.channelMessageSend(message.ChannelID, "you have to tell me what you want :weary:")
 		return
 	}
-	parts = parts[1:]
-	switch {
-	case parts[0] == cmdHelp:
-		b.help(message, parts)
-	case parts[0] == cmdHi:
-		b.m.channelMessageSend(message.ChannelID, "sup sup :sunglasses:")
-	case parts[0] == cmdAsciify:
-		b.asciify(message, parts, false)
-	case parts[0] == cmdAsciifile:
-		b.asciify(message, parts, true)
-	default:
+	c, ok := b.commands.lookup(parts[1])
+	if !ok {
 		b.m.channelMessageSend(message.ChannelID, "sorry, i don't follow :sweat_smile:")
+		return
+	}
+	r := &request{
+		command:     c.Name(),
+		channelID:   message.ChannelID,
+		guildID:     message.GuildID,
+		attachments: message.Attachments,
+		replier:     &messageReplier{m: b.m, channelID: message.ChannelID},
 	}
+	args, err := parseMention(c, parts[2:])
+	if err != nil {
+		b.badParameters(r, err)
+		return
+	}
+	r.args = args
+	c.Handle(b, r)
 }
 
-// printHelp sends the user a quick rundown of the available commands, or a specific command if one was supplied
-func (b *bot) help(message *discordgo.MessageCreate, parts []string) {
-	var sb strings.Builder
-	sb.WriteString("```")
-
-	// if no arguments passed to `help`
-	if len(parts) == 1 {
-		sb.WriteString(fmt.Sprintf("usage: @%s <command> [args ...]\n", b.name))
-		sb.WriteString(fmt.Sprintf("       /%s <command> [args ...]\n\n", b.name))
-		sb.WriteString(fmt.Sprintf("%s: A friendly Discord bot, for fun and development practice\n\n", b.name))
-		sb.WriteString(fmt.Sprintf("%s listens for your mentions or slash commands and responds or acts accordingly\n\n", b.name))
-		sb.WriteString("Commands:\n")
-		for command, description := range cmdHelps {
-			sb.WriteString(fmt.Sprintf("  %-16s%s\n", command, description))
-		}
-	} else {
-		sb.WriteString("specific command help info not implemented yet...")
+// badParameters tells the user how a command is used, and what was wrong with how they used it.
+// Slash command users get Discord's own hints for each option, so they're shown the command rather than the mention usage.
+func (b *bot) badParameters(r *request, err error) {
+	if r.slash {
+		r.reply(fmt.Sprintf("ope, bad parameters, for `/%s %s` %s :face_with_open_eyes_and_hand_over_mouth:", b.name, r.command, err))
+		return
 	}
-
-	sb.WriteString("```")
-	b.m.channelMessageSend(message.ChannelID, sb.String())
+	c, _ := b.commands.lookup(r.command)
+	r.reply(fmt.Sprintf("ope, bad parameters, for `%s %s` %s :face_with_open_eyes_and_hand_over_mouth:", r.command, usage(c), err))
 }
 
-// asciify checks for a png attachment, downloads it to a randomly named file, passes it to the asciify package function, then
-// deletes the download
-func (b *bot) asciify(message *discordgo.MessageCreate, parts []string, toFile bool) {
+// asciify checks for a single image attachment, streams it from the Discord cdn into the asciify package, then replies with
+// the result inline, as an attached TXT, HTML or SVG file, drawn onto an attached PNG, or as an emoji mosaic
+func (b *bot) asciify(r *request, output asciifyOutput) {
 	// validate parameters
-	if len(message.Attachments) == 0 {
-		b.m.channelMessageSend(message.ChannelID, fmt.Sprintf("i can't %s what you don't send me :disappointed:", parts[0]))
+	if len(r.attachments) == 0 {
+		r.reply(fmt.Sprintf("i can't %s what you don't send me :disappointed:", r.command))
 		return
-	} else if len(message.Attachments) > 1 {
-		b.m.channelMessageSend(message.ChannelID, "only send me one attachment, please... :weary:")
+	} else if len(r.attachments) > 1 {
+		r.reply("only send me one attachment, please... :weary:")
 		return
 	}
-	attachment := message.Attachments[0]
-	if !slices.Contains(asciifyAttachmentTypes, attachment.ContentType) {
-		b.m.channelMessageSend(message.ChannelID, fmt.Sprintf("i can only %s png and jpeg attachments :weary:", parts[0]))
+	attachment := r.attachments[0]
+	if r.has(argFormat) {
+		output = asciifyFormats[r.stringArg(argFormat)]
+	}
+	opts, err := parseAsciifyArgs(r, output)
+	if err != nil {
+		b.badParameters(r, err)
 		return
 	}
-	maxWidth, maxHeight := 60, 30 // default keeps it well under the 2000 character limit for non-Nitro messages
-	if toFile {
-		maxWidth, maxHeight = 256, 128
-	}
-	if len(parts) > 1 {
-		if len(parts) != 3 {
-			b.m.channelMessageSend(message.ChannelID, fmt.Sprintf("ope, bad parameters, I need `%s [maxWidth maxHeight]` :face_with_open_eyes_and_hand_over_mouth:", parts[0]))
-			return
-		}
-		width, err := strconv.Atoi(parts[1])
-		if err != nil || width > 60 {
-			b.m.channelMessageSend(message.ChannelID, fmt.Sprintf("ope, bad parameters, for `%s [maxWidth maxHeight]` I need `maxWidth` to be an integer no greater than %d :face_with_open_eyes_and_hand_over_mouth:", parts[0], maxWidth))
-			return
-		}
-		height, err := strconv.Atoi(parts[2])
-		if err != nil || height > 30 {
-			b.m.channelMessageSend(message.ChannelID, fmt.Sprintf("ope, bad parameters, for `%s [maxWidth maxHeight]` I need `maxHeight` to be an integer no greater than %d :face_with_open_eyes_and_hand_over_mouth:", parts[0], maxHeight))
-			return
-		}
-		maxWidth, maxHeight = width, height
+
+	// downloading and converting can take longer than Discord waits for a slash command to be answered
+	r.deferReply()
+
+	// leave room for the reply text and code block around the output, since escape sequences can blow way past the usual size
+	reply := ":white_check_mark: asciified: :nerd:\n"
+	toFile := output != outputInline
+	if !toFile {
+		opts.MaxChars = discordMessageLimit - utf8.RuneCountInString(reply+codeBlock("", opts.Color))
 	}
 
-	// download attachment
-	filename := fmt.Sprintf("%s.png", uuid.New().String())
-	if err := b.download(attachment.URL, filename); err != nil {
+	// stream the attachment straight into the decoder, as long as it isn't too big to bother with
+	if attachment.Size > asciifyMaxDownload {
+		b.asciifyFailed(r, errDownloadTooLarge)
+		return
+	}
+	body, err := b.download(attachment.URL, asciifyMaxDownload)
+	if errors.Is(err, errDownloadTooLarge) {
+		b.asciifyFailed(r, err)
+		return
+	} else if err != nil {
 		slog.Error("failed to download attachment", slog.Any("error", err))
-		b.m.channelMessageSend(message.ChannelID, ":x: sorry, i couldn't download your image :grimmace:")
+		r.reply(":x: sorry, i couldn't download your image :grimmace:")
 		return
 	}
-	defer os.Remove(filename)
+	defer body.Close()
 
-	ascii, err := asciify.Asciify(filename, maxWidth, maxHeight)
+	// trust the attachment's content over its name or declared type
+	format, _, img, err := asciify.Sniff(body)
+	if errors.Is(err, asciify.ErrUnsupportedFormat) {
+		slog.Info("rejected attachment", slog.String("contentType", attachment.ContentType), slog.Any("error", err))
+		r.reply(fmt.Sprintf("i can only %s %s images :weary:", r.command, strings.Join(asciify.Formats, ", ")))
+		return
+	} else if err != nil {
+		b.asciifyFailed(r, err)
+		return
+	}
+	if output == outputMosaic {
+		b.mosaic(r, img, opts)
+		return
+	}
+	if _, ok := asciifyWriters[output]; ok {
+		b.asciiwrite(r, img, opts, output, attachment.Filename)
+		return
+	}
+	if format == "gif" {
+		b.asciifyGIF(r, img, opts, toFile, reply, txtFilename(attachment.Filename))
+		return
+	}
+	ascii, err := asciify.AsciifyReader(img, opts)
 	if err != nil {
-		slog.Error("failed to asciify attachment", slog.Any("error", err))
-		b.m.channelMessageSend(message.ChannelID, fmt.Sprintf(":x: sorry, i couldn't %s that :grimmace:", parts[0]))
+		b.asciifyFailed(r, err)
 		return
 	}
 	if toFile {
-		outFilename := fmt.Sprintf("%s.txt", filename[:strings.LastIndex(filename, ".")])
-		if err := b.createTxt(outFilename, ascii); err != nil {
-			b.m.channelMessageSend(message.ChannelID, ":x: sorry, i couldn't write that file :grimmace:")
-		}
-		defer os.Remove(outFilename)
-		b.m.channelMessageSendWithFile(message.ChannelID, ":white_check_mark: asciifiled: :nerd:", outFilename)
+		r.replyWithReader(":white_check_mark: asciifiled: :nerd:", txtFilename(attachment.Filename), strings.NewReader(ascii))
 	} else {
-		b.m.channelMessageSend(message.ChannelID, fmt.Sprintf(":white_check_mark: asciified: :nerd:\n```%s```", ascii))
+		r.reply(reply + codeBlock(ascii, opts.Color))
 	}
 }
 
-// download fetches the attachment from the Discord cdn and writes it to disk
-func (b *bot) download(url string, filename string) error {
-	out, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	// Get the data
+// download starts fetching the attachment from the Discord cdn, returning the response body for the caller to read and close.
+// Reading more than limit bytes from the body fails with errDownloadTooLarge, whatever size the attachment claimed to be.
+func (b *bot) download(url string, limit int64) (io.ReadCloser, error) {
 	resp, err := http.Get(url)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status downloading attachment: %s", resp.Status)
+	}
+	if resp.ContentLength > limit {
+		resp.Body.Close()
+		return nil, errDownloadTooLarge
 	}
-	defer resp.Body.Close()
+	return &limitedBody{ReadCloser: resp.Body, remaining: limit}, nil
+}
 
-	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return err
+// limitedBody wraps a response body, failing once more than the remaining number of bytes have been read.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, errDownloadTooLarge
 	}
-	return nil
+	// read one byte past the limit, so a body of exactly the limit doesn't look too large
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return 0, errDownloadTooLarge
+	}
+	return n, err
 }
 
-// createTxt creates a .txt file whose contents are a given string
-func (b *bot) createTxt(filename string, content string) error {
-	out, err := os.Create(filename)
-	if err != nil {
-		slog.Error("failed to create asciified file", slog.Any("error", err))
-		return err
+// txtFilename swaps the extension of an attachment's filename for .txt, so the asciified file is named after the original
+func txtFilename(filename string) string {
+	return swapExtension(filename, ".txt")
+}
+
+func swapExtension(filename string, extension string) string {
+	if i := strings.LastIndex(filename, "."); i > 0 {
+		filename = filename[:i]
+	}
+	if filename == "" {
+		filename = "asciified"
 	}
-	defer out.Close()
-	io.WriteString(out, content)
-	return nil
+	return filename + extension
 }
 
 // interactionCreate handles Discord INTERACTION_CREATE events, specifically new application "slash" commands.
@@ -253,42 +353,35 @@ func (b *bot) interactionCreate(interaction *discordgo.InteractionCreate) {
 	}
 	slog.Debug("interaction application command data=" + string(json))
 
-	// respond to the command
-	err = b.s.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: ":question: you know as much as I do, dawg...",
-			Flags:   discordgo.MessageFlagsEphemeral,
-		},
-	})
-	if err != nil {
-		slog.Error("failed to respond to interaction", slog.Any("error", err))
+	// the bot's only slash command has a subcommand for each command it knows
+	if data.Name != b.name || len(data.Options) != 1 || data.Options[0].Type != discordgo.ApplicationCommandOptionSubCommand {
+		slog.Info("ignoring unknown application command", slog.String("name", data.Name))
+		return
+	}
+	replier := &interactionReplier{m: b.m, interaction: interaction.Interaction}
+	c, ok := b.commands.lookup(data.Options[0].Name)
+	if !ok {
+		replier.reply(":question: you know as much as I do, dawg...")
+		return
 	}
+	args, attachments := parseInteraction(c, data.Options[0].Options, data.Resolved)
+	c.Handle(b, &request{
+		command:     c.Name(),
+		channelID:   interaction.ChannelID,
+		guildID:     interaction.GuildID,
+		attachments: attachments,
+		args:        args,
+		slash:       true,
+		replier:     replier,
+	})
 }
 
 // registerCommands tells Discord what application "slash" commands users can call when interacting with the bot, which also
 // provides the users with auto-complete and tooltips.
 func (b *bot) registerCommands() {
-	command := &discordgo.ApplicationCommand{
-		Name:        b.name,
-		Description: fmt.Sprintf("A friendly Discord bot named %s", b.name),
-		Options: []*discordgo.ApplicationCommandOption{
-			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "command",
-				Description: fmt.Sprintf("Get information about %s", b.name),
-				Required:    true,
-				Choices: []*discordgo.ApplicationCommandOptionChoice{
-					{
-						Name:  cmdHelp,
-						Value: cmdHelp,
-					},
-				},
-			},
-		},
-	}
-	_, err := b.s.ApplicationCommandCreate(instance.id, "", command)
+	command := b.commands.applicationCommand(b.name, fmt.Sprintf("A friendly Discord bot named %s", b.name))
+	_, err := b.s.ApplicationCommandBulkOverwrite(b.id, "", []*discordgo.ApplicationCommand{command})
 	if err != nil {
-		slog.Error("failed to create slash command", slog.Any("error", err))
+		slog.Error("failed to register slash commands", slog.Any("error", err))
 	}
 }
@@ -0,0 +1,451 @@
+package bot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmmonosmith/cuddle-bot/asciify"
+)
+
+const (
+	// discordMessageLimit is the most characters a non-Nitro user or bot can send in one message
+	discordMessageLimit = 2000
+
+	// inline output stays well under the 2000 character limit for non-Nitro messages
+	asciifyMaxWidth, asciifyMaxHeight = 60, 30
+	// attached files have no such limit, but still shouldn't be absurdly large
+	asciifileMaxWidth, asciifileMaxHeight = 256, 128
+
+	// attachments are rejected past these sizes before they can eat up all the memory, which happens quickly when a small
+	// compressed file decodes to an enormous image
+	asciifyMaxDownload = 25 << 20
+	asciifyMaxPixels   = 40_000_000
+
+	// animations are played by editing a message, and Discord rate limits edits to about one a second, so inline GIFs are
+	// capped at a number of frames and played through for a limited time
+	gifMaxFrames, gifFileMaxFrames = 20, 100
+	gifMinFrameDelay               = time.Second
+	gifMaxPlayTime                 = 30 * time.Second
+
+	argImage    = "image"
+	argWidth    = "width"
+	argHeight   = "height"
+	argInvert   = "invert"
+	argRamp     = "ramp"
+	argPreset   = "preset"
+	argResample = "resample"
+	argDither   = "dither"
+	argMode     = "mode"
+	argColor    = "color"
+	argBlend    = "blend"
+	argFit      = "fit"
+	argCrop     = "crop"
+	argAspect   = "aspect"
+
+	argBrightness = "brightness"
+	argContrast   = "contrast"
+	argGamma      = "gamma"
+	argMatte      = "matte"
+	argAuto       = "auto"
+	argEqualize   = "equalize"
+
+	argFormat = "format"
+)
+
+// errDownloadTooLarge is returned when an attachment is bigger than asciifyMaxDownload.
+var errDownloadTooLarge = fmt.Errorf("attachment is larger than %d bytes", asciifyMaxDownload)
+
+// asciifyOutput is where the asciify family of commands puts their result.
+type asciifyOutput int
+
+const (
+	outputInline asciifyOutput = iota
+	outputFile
+	outputImage
+	outputHTML
+	outputSVG
+	outputMosaic
+)
+
+// asciifyFormats are the file formats asciifile can attach, besides plain text.
+var asciifyFormats = map[string]asciifyOutput{
+	"txt":  outputFile,
+	"html": outputHTML,
+	"svg":  outputSVG,
+}
+
+// asciifyWriter encodes a rendered grid for one of the outputs that need more than plain text.
+type asciifyWriter struct {
+	write func(io.Writer, *asciify.Grid, asciify.Options) error
+	// suffix replaces the extension of the attachment's filename
+	suffix string
+	reply  string
+}
+
+var asciifyWriters = map[asciifyOutput]asciifyWriter{
+	outputImage: {write: asciify.WritePNG, suffix: "-ascii.png", reply: ":white_check_mark: asciimaged: :frame_photo:"},
+	outputHTML:  {write: asciify.WriteHTML, suffix: ".html", reply: ":white_check_mark: asciifiled: :globe_with_meridians:"},
+	outputSVG:   {write: asciify.WriteSVG, suffix: ".svg", reply: ":white_check_mark: asciifiled: :art:"},
+}
+
+// imageArgs are the image and output size arguments to any command that asciifies an image, with the size limited to
+// maxWidth by maxHeight.
+func imageArgs(maxWidth int, maxHeight int) []Arg {
+	return []Arg{
+		{Name: argImage, Description: "the image to convert", Type: ArgAttachment, Required: true},
+		{Name: argWidth, Description: "the most characters across", Type: ArgInteger, Positional: true, Min: 1, Max: float64(maxWidth), Default: strconv.Itoa(maxWidth)},
+		{Name: argHeight, Description: "the most lines down", Type: ArgInteger, Positional: true, Min: 1, Max: float64(maxHeight), Default: strconv.Itoa(maxHeight)},
+	}
+}
+
+// fitArg and cropArg pick which part of an image is drawn, and how it's sized, for any command that asciifies an image.
+var (
+	fitArg  = Arg{Name: argFit, Description: "how the image is sized to the width and height", Type: ArgString, Choices: []string{"fit", "fill", "stretch"}, Default: "fit"}
+	cropArg = Arg{Name: argCrop, Description: "the part of the image to draw, in pixels", Type: ArgString, Hint: "x,y,w,h", Default: "the whole image"}
+)
+
+// toneArgs adjust an image before it's asciified, for any command that asciifies one. The adjustments that depend on the
+// whole image's levels are in renderArgs instead, since mosaic only adjusts each color on its own.
+var toneArgs = []Arg{
+	{Name: argBrightness, Description: "brighten or darken the image", Type: ArgNumber, Hint: "-1 to 1", Min: -1, Max: 1, Default: "0"},
+	{Name: argContrast, Description: "scale the image's contrast", Type: ArgNumber, Hint: "0.1 to 10", Min: 0.1, Max: 10, Default: "1"},
+	{Name: argGamma, Description: "apply a gamma curve to the image", Type: ArgNumber, Hint: "0.1 to 10", Min: 0.1, Max: 10, Default: "1"},
+	{Name: argMatte, Description: "the luminance transparent areas are drawn over, where 0 is black", Type: ArgNumber, Hint: "0 to 1", Min: 0, Max: 1, Default: "0"},
+}
+
+// filePresets are the ramp presets a command can pick when its output is attached, and inlinePresets when it's sent in a
+// message, which leaves out the default preset since a run of its backticks would close the message's code block.
+var (
+	filePresets   = []string{"discord", "discord16", "gomono", "gomono16", "blocks", "default"}
+	inlinePresets = slices.DeleteFunc(slices.Clone(filePresets), func(preset string) bool { return preset == "default" })
+)
+
+// renderArgs are the arguments picking how an image is asciified, for the asciify, asciifile, and asciimage commands, with
+// the given ramp presets to pick from.
+func renderArgs(presets []string) []Arg {
+	return slices.Concat([]Arg{
+		{Name: argMode, Description: "how characters are picked", Type: ArgString, Choices: []string{"ramp", "braille", "halfblock", "edges", "glyph"}, Default: "ramp"},
+		{Name: argBlend, Description: "fill the space between edges in edges mode", Type: ArgBoolean},
+		{Name: argColor, Description: "color each character from a palette", Type: ArgString, Choices: []string{"discord", "256", "truecolor"}, Bare: "discord", Default: "none"},
+		{Name: argInvert, Description: "draw a negative of the image", Type: ArgBoolean},
+		{Name: argRamp, Description: "the characters to draw, from darkest to lightest", Type: ArgString, Hint: "chars", Default: "the discord preset"},
+		{Name: argPreset, Description: "a predefined set of characters to draw", Type: ArgString, Choices: presets, Default: "discord"},
+		{Name: argResample, Description: "how pixels are combined into each character", Type: ArgString, Choices: []string{"nearest", "box", "bilinear", "lanczos"}, Default: "box"},
+		{Name: argDither, Description: "how error is spread between characters", Type: ArgString, Choices: []string{"none", "fs", "atkinson", "bayer"}, Default: "none"},
+		fitArg,
+		cropArg,
+		{Name: argAspect, Description: "the width of a character divided by its height", Type: ArgNumber, Hint: "cell width/height", Min: 0.1, Max: 10, Default: strconv.FormatFloat(asciify.DefaultCellAspect, 'g', -1, 64)},
+		{Name: argAuto, Description: "stretch the image's levels to use the full range", Type: ArgBoolean},
+		{Name: argEqualize, Description: "equalize the image's histogram", Type: ArgBoolean},
+	}, toneArgs)
+}
+
+// asciifileArgs are the extra arguments to asciifile.
+var asciifileArgs = []Arg{
+	{Name: argFormat, Description: "the type of file to attach", Type: ArgString, Choices: []string{"txt", "html", "svg"}, Default: "txt"},
+}
+
+// parseAsciifyArgs turns the arguments to an asciify, asciifile, asciimage, or mosaic command into asciify options. Errors are
+// meant to be shown to the user as-is.
+func parseAsciifyArgs(r *request, output asciifyOutput) (asciify.Options, error) {
+	limitWidth, limitHeight := asciifileMaxWidth, asciifileMaxHeight
+	switch output {
+	case outputInline:
+		limitWidth, limitHeight = asciifyMaxWidth, asciifyMaxHeight
+	case outputMosaic:
+		limitWidth, limitHeight = mosaicMaxWidth, mosaicMaxHeight
+	}
+	toFile := output != outputInline && output != outputMosaic
+	// Discord's default theme is dark, so draw for that unless told otherwise, with a ramp calibrated for its code blocks, and
+	// average whole cells so photos don't alias
+	opts := asciify.Options{
+		MaxWidth:   limitWidth,
+		MaxHeight:  limitHeight,
+		Ramp:       asciify.RampDiscord,
+		Background: asciify.BackgroundDark,
+		Resample:   asciify.ResampleBox,
+		MaxPixels:  asciifyMaxPixels,
+	}
+
+	// in a mention the sizes are positional, so they come as a pair or not at all, but slash command options are independent
+	// and whichever one is left out stays at the limit
+	if !r.slash && r.has(argWidth) != r.has(argHeight) {
+		return opts, fmt.Errorf("I need both `%s` and `%s`, or neither", argWidth, argHeight)
+	}
+	if r.has(argWidth) {
+		width := r.intArg(argWidth)
+		if width < 1 || width > limitWidth {
+			return opts, fmt.Errorf("I need `%s` to be an integer from 1 to %d", argWidth, limitWidth)
+		}
+		opts.MaxWidth = width
+	}
+	if r.has(argHeight) {
+		height := r.intArg(argHeight)
+		if height < 1 || height > limitHeight {
+			return opts, fmt.Errorf("I need `%s` to be an integer from 1 to %d", argHeight, limitHeight)
+		}
+		opts.MaxHeight = height
+	}
+
+	opts.Invert = r.boolArg(argInvert)
+	opts.Blend = r.boolArg(argBlend)
+	opts.Tone.AutoLevels = r.boolArg(argAuto)
+	opts.Tone.Equalize = r.boolArg(argEqualize)
+
+	if r.has(argRamp) && r.has(argPreset) {
+		return opts, fmt.Errorf("I can only use one of `%s=` or `%s=`", argRamp, argPreset)
+	}
+	if r.has(argRamp) {
+		opts.Ramp = r.stringArg(argRamp)
+		if len([]rune(opts.Ramp)) < 2 {
+			return opts, fmt.Errorf("I need `%s=` to have at least 2 characters", argRamp)
+		}
+	}
+	// the choices were already checked against each package's names, so these can only fail if the two disagree
+	var err error
+	if r.has(argPreset) {
+		if opts.Ramp, err = asciify.ParsePreset(r.stringArg(argPreset)); err != nil {
+			return opts, err
+		}
+	}
+	// a run of backticks would close the code block the output is sent in
+	if output == outputInline && strings.ContainsRune(opts.Ramp, '`') {
+		return opts, fmt.Errorf("I can't use backticks in `%s=` in a message, try asciifile for those", argRamp)
+	}
+	if r.has(argResample) {
+		if opts.Resample, err = asciify.ParseResample(r.stringArg(argResample)); err != nil {
+			return opts, err
+		}
+	}
+	if r.has(argDither) {
+		if opts.Dither, err = asciify.ParseDither(r.stringArg(argDither)); err != nil {
+			return opts, err
+		}
+	}
+	if r.has(argFit) {
+		if opts.Fit, err = asciify.ParseFit(r.stringArg(argFit)); err != nil {
+			return opts, err
+		}
+	}
+	if r.has(argMode) {
+		if opts.Mode, err = asciify.ParseMode(r.stringArg(argMode)); err != nil {
+			return opts, err
+		}
+	}
+	// halfblock draws the colors as they are, and the levels are only ever stretched for luminance
+	if opts.Mode == asciify.ModeHalfBlock && (opts.Tone.AutoLevels || opts.Tone.Equalize) {
+		return opts, fmt.Errorf("I can't use `%s` or `%s` with `%s=halfblock`", argAuto, argEqualize, argMode)
+	}
+	if r.has(argColor) {
+		if opts.Color, err = asciify.ParseColorMode(r.stringArg(argColor)); err != nil {
+			return opts, err
+		}
+		// Discord itself only understands its own palette, other modes are for viewing the file in a terminal
+		if !toFile && opts.Color != asciify.ColorNone && opts.Color != asciify.ColorDiscord {
+			return opts, fmt.Errorf("I can only use `%s=discord` in a message, try asciifile for other palettes", argColor)
+		}
+	}
+
+	if r.has(argCrop) {
+		crop, err := parseCrop(r.stringArg(argCrop))
+		if err != nil {
+			return opts, fmt.Errorf("I need `%s=x,y,w,h` to be 4 comma separated integers, with a positive width and height", argCrop)
+		}
+		opts.Crop = crop
+	}
+	if r.has(argAspect) {
+		aspect, err := floatInRange(r, argAspect, 0.1, 10)
+		if err != nil {
+			return opts, err
+		}
+		opts.CellAspect = float64(aspect)
+	}
+	if r.has(argMatte) {
+		v, err := floatInRange(r, argMatte, 0, 1)
+		if err != nil {
+			return opts, fmt.Errorf("I need `%s=` to be a luminance from 0 (black) to 1 (white)", argMatte)
+		}
+		gray := uint8(v*255 + 0.5)
+		opts.Matte = color.Gray{Y: gray}
+	}
+	if r.has(argBrightness) {
+		if opts.Tone.Brightness, err = floatInRange(r, argBrightness, -1, 1); err != nil {
+			return opts, err
+		}
+	}
+	if r.has(argContrast) {
+		if opts.Tone.Contrast, err = floatInRange(r, argContrast, 0.1, 10); err != nil {
+			return opts, err
+		}
+	}
+	if r.has(argGamma) {
+		if opts.Tone.Gamma, err = floatInRange(r, argGamma, 0.1, 10); err != nil {
+			return opts, err
+		}
+	}
+
+	// half blocks are all color, and a message can only show Discord's palette
+	if opts.Mode == asciify.ModeHalfBlock && opts.Color == asciify.ColorNone && !toFile {
+		opts.Color = asciify.ColorDiscord
+	}
+	return opts, nil
+}
+
+// floatInRange returns a number argument that has to be within [lo, hi].
+func floatInRange(r *request, name string, lo float32, hi float32) (float32, error) {
+	v := float32(r.floatArg(name))
+	if v < lo || v > hi {
+		return 0, fmt.Errorf("I need `%s=` to be a number from %g to %g", name, lo, hi)
+	}
+	return v, nil
+}
+
+// parseCrop parses a crop rectangle given as x,y,w,h.
+func parseCrop(value string) (image.Rectangle, error) {
+	fields := strings.Split(value, ",")
+	if len(fields) != 4 {
+		return image.Rectangle{}, errors.New("crop needs 4 fields")
+	}
+	var n [4]int
+	for i, field := range fields {
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return image.Rectangle{}, err
+		}
+		n[i] = v
+	}
+	if n[2] < 1 || n[3] < 1 {
+		return image.Rectangle{}, errors.New("crop must be wider/taller than 0")
+	}
+	return image.Rect(n[0], n[1], n[0]+n[2], n[1]+n[3]), nil
+}
+
+// codeBlock wraps asciified text in a code block, using Discord's ansi highlighting when the text has color escape sequences.
+// The text starts on its own line so its first row can't be mistaken for the block's language.
+func codeBlock(ascii string, color asciify.ColorMode) string {
+	language := ""
+	if color != asciify.ColorNone {
+		language = "ansi"
+	}
+	return fmt.Sprintf("```%s\n%s```", language, ascii)
+}
+
+// splitArgs splits message content on spaces, keeping "double quoted" runs together (without the quotes) so arguments like
+// ramp=" .:-=+*#%@" can contain spaces.
+func splitArgs(content string) []string {
+	var parts []string
+	var sb strings.Builder
+	quoted, started := false, false
+	for _, c := range content {
+		switch {
+		case c == '"':
+			quoted, started = !quoted, true
+		case c == ' ' && !quoted:
+			if started {
+				parts = append(parts, sb.String())
+				sb.Reset()
+				started = false
+			}
+		default:
+			sb.WriteRune(c)
+			started = true
+		}
+	}
+	if started {
+		parts = append(parts, sb.String())
+	}
+	return parts
+}
+
+// asciifyGIF asciifies every frame of a GIF, then either attaches all the frames as one TXT file, or animates them by editing
+// a single message for a while.
+func (b *bot) asciifyGIF(r *request, body io.Reader, opts asciify.Options, toFile bool, reply string, filename string) {
+	maxFrames := gifMaxFrames
+	if toFile {
+		maxFrames = gifFileMaxFrames
+	}
+	anim, err := asciify.AsciifyGIF(body, opts, maxFrames)
+	if err != nil {
+		b.asciifyFailed(r, err)
+		return
+	}
+
+	if toFile {
+		var sb strings.Builder
+		for i, frame := range anim.Frames {
+			sb.WriteString(fmt.Sprintf("--- frame %d/%d (%s) ---\n", i+1, len(anim.Frames), anim.Delays[i]))
+			sb.WriteString(frame)
+		}
+		r.replyWithReader(":white_check_mark: asciifiled: :nerd:", filename, strings.NewReader(sb.String()))
+		return
+	}
+
+	edit := r.replyEditable(reply + codeBlock(anim.Frames[0], opts.Color))
+	if edit == nil || len(anim.Frames) == 1 {
+		return
+	}
+	b.animate(edit, reply, anim, opts.Color)
+}
+
+// animate cycles through the frames of an animation by editing a message, looping until it has played for a while. Each frame
+// is shown for at least a second, no matter how fast the original was, to stay clear of Discord's rate limits.
+func (b *bot) animate(edit func(string) error, reply string, anim *asciify.Animation, color asciify.ColorMode) {
+	var played time.Duration
+	for i := 0; played < gifMaxPlayTime; i = (i + 1) % len(anim.Frames) {
+		delay := max(anim.Delays[i], gifMinFrameDelay)
+		time.Sleep(delay)
+		played += delay
+		next := (i + 1) % len(anim.Frames)
+		if err := edit(reply + codeBlock(anim.Frames[next], color)); err != nil {
+			return
+		}
+	}
+}
+
+// asciiwrite asciifies an image, encodes it as a PNG, HTML or SVG file depending on the output, and attaches that to the reply.
+// Only the first frame of a GIF is written.
+func (b *bot) asciiwrite(r *request, body io.Reader, opts asciify.Options, output asciifyOutput, filename string) {
+	writer := asciifyWriters[output]
+	m, err := asciify.Decode(body, opts)
+	if err != nil {
+		b.asciifyFailed(r, err)
+		return
+	}
+	g, err := asciify.Render(m, opts)
+	if err != nil {
+		b.asciifyFailed(r, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := writer.write(&buf, g, opts); err != nil {
+		slog.Error("failed to write asciified attachment", slog.Any("error", err))
+		r.reply(":x: sorry, i couldn't draw that :grimmace:")
+		return
+	}
+	r.replyWithReader(writer.reply, swapExtension(filename, writer.suffix), &buf)
+}
+
+// asciifyFailed logs why an image couldn't be asciified and tells the user, with a friendlier explanation when the image was
+// simply too big.
+func (b *bot) asciifyFailed(r *request, err error) {
+	switch {
+	case errors.Is(err, errDownloadTooLarge):
+		slog.Info("rejected attachment", slog.Any("error", err))
+		r.reply(fmt.Sprintf(":x: that file is too big for me, keep it under %d MiB please :weary:", asciifyMaxDownload>>20))
+	case errors.Is(err, asciify.ErrTooLarge):
+		slog.Info("rejected attachment", slog.Any("error", err))
+		r.reply(fmt.Sprintf(":x: that image is too big for me, keep it under %d megapixels please :weary:", asciifyMaxPixels/1_000_000))
+	default:
+		slog.Error("failed to asciify attachment", slog.Any("error", err))
+		r.reply(fmt.Sprintf(":x: sorry, i couldn't %s that :grimmace:", r.command))
+	}
+}
@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cmmonosmith/cuddle-bot/asciify"
+	"github.com/cmmonosmith/cuddle-bot/figlet"
+)
+
+// banners wrap at the same width as inline asciify output, so they don't wrap again in narrow Discord windows
+const bannerMaxWidth = asciifyMaxWidth
+
+// bannerArgs are the arguments to the banner command.
+var bannerArgs = []Arg{
+	{Name: "font", Description: "the FIGlet font to draw with", Type: ArgString, Required: true, Choices: figlet.Fonts, Positional: true},
+	{Name: "text", Description: "the text to draw", Type: ArgString, Required: true, Rest: true},
+}
+
+// banner draws some text as big letters in a FIGlet font, and replies with them in a code block.
+func (b *bot) banner(r *request) {
+	font, err := figlet.Load(r.stringArg("font"))
+	if err != nil {
+		slog.Info("rejected banner font", slog.Any("error", err))
+		r.reply(fmt.Sprintf("i don't know that font, try one of %s :sweat_smile:", strings.Join(figlet.Fonts, ", ")))
+		return
+	}
+
+	// leave room for the reply text and code block around the banner, the same as asciify
+	reply := ":white_check_mark: bannered: :triangular_flag_on_post:\n"
+	maxChars := discordMessageLimit - utf8.RuneCountInString(reply+codeBlock("", asciify.ColorNone))
+	text := font.Render(r.stringArg("text"), bannerMaxWidth)
+	switch {
+	case strings.TrimSpace(text) == "":
+		r.reply("i can't draw any of those characters :weary:")
+	case utf8.RuneCountInString(text) > maxChars:
+		r.reply("that's too much text for one message, try fewer words or the braille font :weary:")
+	default:
+		r.reply(reply + codeBlock(text, asciify.ColorNone))
+	}
+}
@@ -0,0 +1,441 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Command is something the bot can do when asked, either by mentioning it with the command's name followed by arguments, or
+// with the matching subcommand of the bot's slash command. Both are generated from the same description of the command's
+// arguments, so every command works the same either way.
+type Command interface {
+	// Name is what the command is called, which must be lowercase to be a valid slash subcommand.
+	Name() string
+	// Description is a one line summary of what the command does, at most 100 characters for Discord.
+	Description() string
+	// Args describes the arguments the command takes.
+	Args() []Arg
+	// Help describes the command in more detail, for the help command.
+	Help() Help
+	// Handle carries out the command and replies to it.
+	Handle(b *bot, r *request)
+}
+
+// ArgType is the type of value an argument takes.
+type ArgType int
+
+const (
+	ArgString ArgType = iota
+	ArgInteger
+	ArgNumber
+	ArgBoolean
+	// ArgAttachment is a file sent along with the command, which in a mention is simply attached to the message.
+	ArgAttachment
+)
+
+// argTypeNames are how each type is described in usage text and error messages.
+var argTypeNames = map[ArgType]string{
+	ArgString:     "text",
+	ArgInteger:    "an integer",
+	ArgNumber:     "a number",
+	ArgBoolean:    "true or false",
+	ArgAttachment: "a file",
+}
+
+// applicationCommandOptionTypes are the slash command option types for each type.
+var applicationCommandOptionTypes = map[ArgType]discordgo.ApplicationCommandOptionType{
+	ArgString:     discordgo.ApplicationCommandOptionString,
+	ArgInteger:    discordgo.ApplicationCommandOptionInteger,
+	ArgNumber:     discordgo.ApplicationCommandOptionNumber,
+	ArgBoolean:    discordgo.ApplicationCommandOptionBoolean,
+	ArgAttachment: discordgo.ApplicationCommandOptionAttachment,
+}
+
+// Arg describes one argument to a command. In a mention, arguments are given as name=value, booleans can be given by name
+// alone, and positional arguments can be given by value alone, in order. Slash commands get an option for each argument.
+type Arg struct {
+	// Name is what the argument is called, which must be lowercase to be a valid slash command option.
+	Name string
+	// Description is a one line summary of the argument, at most 100 characters for Discord.
+	Description string
+	Type        ArgType
+	Required    bool
+	// Choices, if any, are the only values a string argument can take.
+	Choices []string
+	// Positional arguments can be given in a mention without their name.
+	Positional bool
+	// Rest takes all the remaining words of a mention once the positional arguments are filled, e.g. a banner's text.
+	Rest bool
+	// Bare is the value a string argument takes when it's given by name alone in a mention, e.g. color for color=discord.
+	Bare string
+	// Hint describes the value in usage text, e.g. "chars", defaulting to the argument's choices or type.
+	Hint string
+	// Min and Max, unless both are 0, bound an integer or number argument in the slash command. Mentions aren't checked
+	// against them, so handlers still need to check the value themselves.
+	Min, Max float64
+	// Default describes the value used when the argument isn't given, for help text.
+	Default string
+}
+
+// Help is what the help command says about a command, beyond its description and arguments.
+type Help struct {
+	// Aliases are other names the command can be mentioned by. Slash commands only go by the command's name.
+	Aliases []string
+	// Examples are ways to use the command, as the words following its name in a mention.
+	Examples []string
+}
+
+// command is a Command built from plain values, which is all most commands need.
+type command struct {
+	name        string
+	description string
+	args        []Arg
+	help        Help
+	handle      func(b *bot, r *request)
+}
+
+func (c *command) Name() string              { return c.name }
+func (c *command) Description() string       { return c.description }
+func (c *command) Args() []Arg               { return c.args }
+func (c *command) Help() Help                { return c.help }
+func (c *command) Handle(b *bot, r *request) { c.handle(b, r) }
+
+// registry holds the commands the bot knows, in the order they were registered.
+type registry struct {
+	commands []Command
+}
+
+// register adds commands to the registry, panicking on duplicate names or aliases since that's a programming error.
+func (reg *registry) register(commands ...Command) {
+	for _, c := range commands {
+		for _, name := range append([]string{c.Name()}, c.Help().Aliases...) {
+			if _, ok := reg.lookup(name); ok {
+				panic(fmt.Sprintf("command %s registered twice", name))
+			}
+		}
+		reg.commands = append(reg.commands, c)
+	}
+}
+
+// lookup finds a command by name or alias.
+func (reg *registry) lookup(name string) (Command, bool) {
+	for _, c := range reg.commands {
+		if c.Name() == name || slices.Contains(c.Help().Aliases, name) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// sorted returns the registered commands sorted by name, so listings don't depend on registration order.
+func (reg *registry) sorted() []Command {
+	return slices.SortedFunc(slices.Values(reg.commands), func(a, b Command) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+}
+
+// applicationCommand generates the bot's slash command, which has a subcommand for every registered command, e.g.
+// /cuddlebot asciify.
+func (reg *registry) applicationCommand(name string, description string) *discordgo.ApplicationCommand {
+	options := make([]*discordgo.ApplicationCommandOption, 0, len(reg.commands))
+	for _, c := range reg.commands {
+		options = append(options, subcommand(c))
+	}
+	return &discordgo.ApplicationCommand{
+		Name:        name,
+		Description: description,
+		Options:     options,
+	}
+}
+
+// subcommand generates the slash subcommand for a command, with an option for each argument. Discord wants required options
+// before optional ones, so they're moved to the front.
+func subcommand(c Command) *discordgo.ApplicationCommandOption {
+	args := slices.Clone(c.Args())
+	slices.SortStableFunc(args, func(a, b Arg) int {
+		switch {
+		case a.Required == b.Required:
+			return 0
+		case a.Required:
+			return -1
+		}
+		return 1
+	})
+
+	options := make([]*discordgo.ApplicationCommandOption, 0, len(args))
+	for _, a := range args {
+		option := &discordgo.ApplicationCommandOption{
+			Type:        applicationCommandOptionTypes[a.Type],
+			Name:        a.Name,
+			Description: a.Description,
+			Required:    a.Required,
+		}
+		for _, choice := range a.Choices {
+			option.Choices = append(option.Choices, &discordgo.ApplicationCommandOptionChoice{Name: choice, Value: choice})
+		}
+		if a.Min != 0 || a.Max != 0 {
+			option.MinValue, option.MaxValue = &a.Min, a.Max
+		}
+		options = append(options, option)
+	}
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        c.Name(),
+		Description: c.Description(),
+		Options:     options,
+	}
+}
+
+// usage generates the argument synopsis for a command as it's used in a mention, e.g. "[width] [height] [invert]".
+func usage(c Command) string {
+	var parts []string
+	for _, a := range c.Args() {
+		if a.Type == ArgAttachment {
+			continue
+		}
+		var part string
+		switch {
+		case a.Positional || a.Rest:
+			part = a.Name
+		case a.Type == ArgBoolean:
+			part = a.Name
+		case a.Bare != "":
+			part = a.Name + "[=" + argHint(a) + "]"
+		default:
+			part = a.Name + "=" + argHint(a)
+		}
+		if a.Required {
+			part = "<" + part + ">"
+		} else {
+			part = "[" + part + "]"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// argHint describes the value an argument takes in usage text.
+func argHint(a Arg) string {
+	switch {
+	case a.Hint != "":
+		return "<" + a.Hint + ">"
+	case len(a.Choices) > 0:
+		return strings.Join(a.Choices, "|")
+	case a.Type == ArgString:
+		return "<text>"
+	}
+	return "<" + strings.TrimPrefix(strings.TrimPrefix(argTypeNames[a.Type], "an "), "a ") + ">"
+}
+
+// argHelp describes an argument in help text, with its limits and default, e.g. "the most lines down, from 1 to 30 (default
+// 30)".
+func argHelp(a Arg) string {
+	var sb strings.Builder
+	sb.WriteString(a.Description)
+	switch {
+	case a.Type == ArgAttachment:
+		sb.WriteString(", attached to the message")
+	case len(a.Choices) > 0:
+		sb.WriteString(", one of " + oneOf(a.Choices))
+		if a.Bare != "" {
+			sb.WriteString(fmt.Sprintf(", or %s alone for %s", a.Name, a.Bare))
+		}
+	case a.Min != 0 || a.Max != 0:
+		sb.WriteString(fmt.Sprintf(", from %g to %g", a.Min, a.Max))
+	}
+	switch {
+	case a.Required:
+		sb.WriteString(" (required)")
+	case a.Default != "":
+		sb.WriteString(fmt.Sprintf(" (default %s)", a.Default))
+	}
+	return sb.String()
+}
+
+// parseMention parses the words following a command's name in a mention into its arguments. Errors are meant to be shown to
+// the user as-is.
+func parseMention(c Command, words []string) (map[string]any, error) {
+	args := c.Args()
+	values := map[string]any{}
+	positional := 0
+	for i, word := range words {
+		// once the positional arguments are filled, everything else is the rest
+		next := nextPositional(args, positional)
+		if next < 0 {
+			if rest := slices.IndexFunc(args, func(a Arg) bool { return a.Rest }); rest >= 0 {
+				values[args[rest].Name] = strings.Join(words[i:], " ")
+				break
+			}
+		}
+
+		name, value, named := strings.Cut(word, "=")
+		a := slices.IndexFunc(args, func(a Arg) bool {
+			return !a.Positional && !a.Rest && a.Type != ArgAttachment && a.Name == name
+		})
+		switch {
+		case a >= 0 && named:
+			v, err := parseArgValue(args[a], value)
+			if err != nil {
+				return nil, err
+			}
+			values[name] = v
+		case a >= 0 && args[a].Type == ArgBoolean:
+			values[name] = true
+		case a >= 0 && args[a].Bare != "":
+			values[name] = args[a].Bare
+		case a >= 0:
+			return nil, fmt.Errorf("I need `%s=` to have a value", name)
+		case named:
+			return nil, fmt.Errorf("I don't know what `%s=` means", name)
+		case next >= 0:
+			v, err := parseArgValue(args[next], word)
+			if err != nil {
+				return nil, err
+			}
+			values[args[next].Name] = v
+			positional = next + 1
+		default:
+			return nil, fmt.Errorf("I don't know what `%s` means", word)
+		}
+	}
+
+	// attachments are checked by the handler, since they come with the message rather than its words
+	for _, a := range args {
+		if _, ok := values[a.Name]; a.Required && a.Type != ArgAttachment && !ok {
+			return nil, fmt.Errorf("I need `%s`", a.Name)
+		}
+	}
+	return values, nil
+}
+
+// nextPositional returns the index of the first positional argument at or after from, or -1 if there are none left.
+func nextPositional(args []Arg, from int) int {
+	for i := from; i < len(args); i++ {
+		if args[i].Positional {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseArgValue parses one argument's value from a mention.
+func parseArgValue(a Arg, value string) (any, error) {
+	name := a.Name
+	if !a.Positional {
+		name += "="
+	}
+	var v any
+	var err error
+	switch a.Type {
+	case ArgInteger:
+		v, err = strconv.ParseInt(value, 10, 64)
+	case ArgNumber:
+		v, err = strconv.ParseFloat(value, 64)
+	case ArgBoolean:
+		v, err = strconv.ParseBool(value)
+	default:
+		if len(a.Choices) > 0 && !slices.Contains(a.Choices, value) {
+			return nil, fmt.Errorf("I need `%s` to be one of %s", name, oneOf(a.Choices))
+		}
+		v = value
+	}
+	if err != nil {
+		return nil, fmt.Errorf("I need `%s` to be %s", name, argTypeNames[a.Type])
+	}
+	return v, nil
+}
+
+// parseInteraction reads a slash subcommand's options into its arguments, the same as parseMention does for a mention, and
+// looks up any attachments among them.
+func parseInteraction(c Command, options []*discordgo.ApplicationCommandInteractionDataOption, resolved *discordgo.ApplicationCommandInteractionDataResolved) (map[string]any, []*discordgo.MessageAttachment) {
+	values := map[string]any{}
+	var attachments []*discordgo.MessageAttachment
+	for _, option := range options {
+		a := slices.IndexFunc(c.Args(), func(a Arg) bool { return a.Name == option.Name })
+		if a < 0 {
+			continue
+		}
+		switch c.Args()[a].Type {
+		case ArgInteger:
+			values[option.Name] = option.IntValue()
+		case ArgNumber:
+			values[option.Name] = option.FloatValue()
+		case ArgBoolean:
+			values[option.Name] = option.BoolValue()
+		case ArgAttachment:
+			// the option's value is just an ID, the attachment itself comes separately
+			id, _ := option.Value.(string)
+			if resolved == nil {
+				continue
+			}
+			if attachment, ok := resolved.Attachments[id]; ok {
+				attachments = append(attachments, attachment)
+			}
+		default:
+			values[option.Name] = option.StringValue()
+		}
+	}
+	return values, attachments
+}
+
+// oneOf lists choices for an error message, e.g. "a, b, or c".
+func oneOf(choices []string) string {
+	switch len(choices) {
+	case 0:
+		return ""
+	case 1:
+		return choices[0]
+	case 2:
+		return choices[0] + " or " + choices[1]
+	}
+	return strings.Join(choices[:len(choices)-1], ", ") + ", or " + choices[len(choices)-1]
+}
+
+// request is one use of a command, from either a mention or a slash command.
+type request struct {
+	// command is the name of the command being used
+	command   string
+	channelID string
+	guildID   string
+	// attachments are the files sent along with the command
+	attachments []*discordgo.MessageAttachment
+	// args holds the value of each argument that was given, as a string, int64, float64, or bool depending on its type
+	args map[string]any
+	// slash is whether the command came from a slash command rather than a mention
+	slash bool
+	replier
+}
+
+// has reports whether an argument was given.
+func (r *request) has(name string) bool {
+	_, ok := r.args[name]
+	return ok
+}
+
+// stringArg returns a string argument, or "" if it wasn't given.
+func (r *request) stringArg(name string) string {
+	v, _ := r.args[name].(string)
+	return v
+}
+
+// intArg returns an integer argument, or 0 if it wasn't given.
+func (r *request) intArg(name string) int {
+	v, _ := r.args[name].(int64)
+	return int(v)
+}
+
+// floatArg returns a number argument, or 0 if it wasn't given.
+func (r *request) floatArg(name string) float64 {
+	v, _ := r.args[name].(float64)
+	return v
+}
+
+// boolArg returns a boolean argument, or false if it wasn't given.
+func (r *request) boolArg(name string) bool {
+	v, _ := r.args[name].(bool)
+	return v
+}
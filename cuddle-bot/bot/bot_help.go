@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cmmonosmith/cuddle-bot/asciify"
+)
+
+// helpArgs are the arguments to the help command.
+var helpArgs = []Arg{
+	{Name: "command", Description: "the command to explain, or leave it out to list every command", Type: ArgString, Positional: true},
+}
+
+// help sends the user a quick rundown of the available commands, or a detailed one of a specific command if one was supplied.
+// Detailed help can run past Discord's message limit, so it's split across as many messages as it takes.
+func (b *bot) help(r *request) {
+	// if no arguments passed to `help`
+	if !r.has("command") {
+		r.reply(codeBlock(b.overview(), asciify.ColorNone))
+		return
+	}
+	c, ok := b.commands.lookup(r.stringArg("command"))
+	if !ok {
+		r.reply(fmt.Sprintf("i don't know how to `%s`, try `help` to see what i can do :sweat_smile:", r.stringArg("command")))
+		return
+	}
+	for _, text := range splitLines(b.commandHelp(c), discordMessageLimit-utf8.RuneCountInString(codeBlock("", asciify.ColorNone))) {
+		r.reply(codeBlock(text, asciify.ColorNone))
+	}
+}
+
+// overview lists every command the bot knows, sorted by name.
+func (b *bot) overview() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("usage: @%s <command> [args ...]\n", b.name))
+	sb.WriteString(fmt.Sprintf("       /%s <command> [args ...]\n\n", b.name))
+	sb.WriteString(fmt.Sprintf("%s: A friendly Discord bot, for fun and development practice\n\n", b.name))
+	sb.WriteString(fmt.Sprintf("%s listens for your mentions or slash commands and responds or acts accordingly\n\n", b.name))
+	sb.WriteString("Commands:\n")
+	for _, c := range b.commands.sorted() {
+		sb.WriteString(fmt.Sprintf("  %-16s%s\n", c.Name(), c.Description()))
+	}
+	sb.WriteString(fmt.Sprintf("\nTry @%s %s <command> for more about a command\n", b.name, cmdHelp))
+	return sb.String()
+}
+
+// commandHelp explains a command in detail: how it's used, what each of its arguments does, and some examples.
+func (b *bot) commandHelp(c Command) string {
+	help := c.Help()
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("usage: @%s %s", b.name, c.Name()))
+	if u := usage(c); u != "" {
+		sb.WriteString(" " + u)
+	}
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("       /%s %s\n\n", b.name, c.Name()))
+	sb.WriteString(c.Description() + "\n")
+	if len(help.Aliases) > 0 {
+		sb.WriteString(fmt.Sprintf("\nAliases: %s\n", strings.Join(help.Aliases, ", ")))
+	}
+	if len(c.Args()) > 0 {
+		sb.WriteString("\nArguments:\n")
+		for _, a := range c.Args() {
+			sb.WriteString(fmt.Sprintf("  %-16s%s\n", a.Name, argHelp(a)))
+		}
+	}
+	if len(help.Examples) > 0 {
+		sb.WriteString("\nExamples:\n")
+		for _, example := range help.Examples {
+			sb.WriteString(strings.TrimRight(fmt.Sprintf("  @%s %s %s", b.name, c.Name(), example), " ") + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// splitLines splits text into pieces of at most limit characters, breaking only between lines. Lines longer than the limit
+// are left whole, since there's nowhere good to break them.
+func splitLines(text string, limit int) []string {
+	var pieces []string
+	var sb strings.Builder
+	n := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		length := utf8.RuneCountInString(line)
+		if n > 0 && n+length > limit {
+			pieces = append(pieces, sb.String())
+			sb.Reset()
+			n = 0
+		}
+		sb.WriteString(line)
+		n += length
+	}
+	if n > 0 {
+		pieces = append(pieces, sb.String())
+	}
+	return pieces
+}
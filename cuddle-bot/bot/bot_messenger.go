@@ -1,8 +1,9 @@
 package bot
 
 import (
+	"io"
 	"log/slog"
-	"os"
+	"sync"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -24,14 +25,208 @@ func (m *messenger) channelMessageSend(channelID string, message string) {
 	}
 }
 
-// channelMessageSendWithFile wraps the session ChannelMessageSendComplex function to attach a file and log any errors
-func (m *messenger) channelMessageSendWithFile(channelID string, message string, filename string) {
-	reader, err := os.Open(filename)
+// channelMessageSendMessage wraps the session ChannelMessageSend function to log any errors, returning the sent message (or
+// nil if it failed) for callers that need to refer back to it
+func (m *messenger) channelMessageSendMessage(channelID string, message string) *discordgo.Message {
+	sent, err := m.s.ChannelMessageSend(channelID, message)
 	if err != nil {
-		slog.Error("failed to open file for sending", slog.Any("error", err))
-		m.channelMessageSend(channelID, ":x: sorry, i couldn't open the file to send :grimmace:")
+		slog.Error("failed to send channel message", slog.Any("error", err))
+		return nil
+	}
+	return sent
+}
+
+// channelMessageEdit wraps the session ChannelMessageEdit function to log any errors, which are also returned so callers
+// repeatedly editing a message know when to give up
+func (m *messenger) channelMessageEdit(channelID string, messageID string, message string) error {
+	_, err := m.s.ChannelMessageEdit(channelID, messageID, message)
+	if err != nil {
+		slog.Error("failed to edit channel message", slog.Any("error", err))
+	}
+	return err
+}
+
+// channelMessageSendWithReader wraps the session ChannelMessageSendComplex function to attach in-memory content as a file
+// named filename and log any errors
+func (m *messenger) channelMessageSendWithReader(channelID string, message string, filename string, reader io.Reader) {
+	_, err := m.s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{File: &discordgo.File{Name: filename, Reader: reader}, Content: message})
+	if err != nil {
+		slog.Error("failed to send channel message with file", slog.Any("error", err))
+	}
+}
+
+// channelTyping wraps the session ChannelTyping function to log any errors
+func (m *messenger) channelTyping(channelID string) {
+	if err := m.s.ChannelTyping(channelID); err != nil {
+		slog.Error("failed to send typing indicator", slog.Any("error", err))
+	}
+}
+
+// interactionRespond wraps the session InteractionRespond function to log any errors, which are also returned so callers know
+// whether the interaction was answered
+func (m *messenger) interactionRespond(interaction *discordgo.Interaction, response *discordgo.InteractionResponse) error {
+	err := m.s.InteractionRespond(interaction, response)
+	if err != nil {
+		slog.Error("failed to respond to interaction", slog.Any("error", err))
+	}
+	return err
+}
+
+// followupMessageCreate wraps the session FollowupMessageCreate function to log any errors, returning the sent message (or nil
+// if it failed) for callers that need to refer back to it
+func (m *messenger) followupMessageCreate(interaction *discordgo.Interaction, params *discordgo.WebhookParams) *discordgo.Message {
+	sent, err := m.s.FollowupMessageCreate(interaction, true, params)
+	if err != nil {
+		slog.Error("failed to send followup message", slog.Any("error", err))
+		return nil
+	}
+	return sent
+}
+
+// interactionResponseEdit wraps the session InteractionResponseEdit function to log any errors, which are also returned so
+// callers repeatedly editing a response know when to give up
+func (m *messenger) interactionResponseEdit(interaction *discordgo.Interaction, edit *discordgo.WebhookEdit) error {
+	_, err := m.s.InteractionResponseEdit(interaction, edit)
+	if err != nil {
+		slog.Error("failed to edit interaction response", slog.Any("error", err))
+	}
+	return err
+}
+
+// followupMessageEdit wraps the session FollowupMessageEdit function to log any errors, which are also returned so callers
+// repeatedly editing a message know when to give up
+func (m *messenger) followupMessageEdit(interaction *discordgo.Interaction, messageID string, edit *discordgo.WebhookEdit) error {
+	_, err := m.s.FollowupMessageEdit(interaction, messageID, edit)
+	if err != nil {
+		slog.Error("failed to edit followup message", slog.Any("error", err))
+	}
+	return err
+}
+
+// replier sends the replies to a request, so commands don't have to care whether they were asked in a message or with a slash
+// command.
+type replier interface {
+	// deferReply lets the user know the reply is coming, for commands that may take a while
+	deferReply()
+	// reply sends a reply
+	reply(content string)
+	// replyWithReader sends a reply with in-memory content attached as a file named filename
+	replyWithReader(content string, filename string, reader io.Reader)
+	// replyEditable sends a reply, returning a function that replaces its content, or nil if it couldn't be sent
+	replyEditable(content string) func(content string) error
+}
+
+// messageReplier replies to a mention with messages in the same channel.
+type messageReplier struct {
+	m         *messenger
+	channelID string
+}
+
+func (r *messageReplier) deferReply() {
+	r.m.channelTyping(r.channelID)
+}
+
+func (r *messageReplier) reply(content string) {
+	r.m.channelMessageSend(r.channelID, content)
+}
+
+func (r *messageReplier) replyWithReader(content string, filename string, reader io.Reader) {
+	r.m.channelMessageSendWithReader(r.channelID, content, filename, reader)
+}
+
+func (r *messageReplier) replyEditable(content string) func(content string) error {
+	sent := r.m.channelMessageSendMessage(r.channelID, content)
+	if sent == nil {
+		return nil
+	}
+	return func(content string) error {
+		return r.m.channelMessageEdit(r.channelID, sent.ID, content)
+	}
+}
+
+// interactionState is how far along answering an interaction is.
+type interactionState int
+
+const (
+	interactionUnanswered interactionState = iota
+	// interactionDeferred means Discord was told an answer is coming, which it shows as the bot thinking
+	interactionDeferred
+	interactionAnswered
+)
+
+// interactionReplier replies to a slash command, answering the interaction itself the first time and sending followup
+// messages after that, since an interaction can only be answered once. Discord gives up on interactions that aren't answered
+// within 3 seconds, so slow commands defer their answer first, which gives them 15 minutes to fill it in.
+type interactionReplier struct {
+	m           *messenger
+	interaction *discordgo.Interaction
+
+	mu    sync.Mutex
+	state interactionState
+}
+
+func (r *interactionReplier) deferReply() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state != interactionUnanswered {
 		return
 	}
-	defer reader.Close()
-	m.s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{File: &discordgo.File{Name: filename, Reader: reader}, Content: message})
+	err := r.m.interactionRespond(r.interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err == nil {
+		r.state = interactionDeferred
+	}
+}
+
+func (r *interactionReplier) reply(content string) {
+	r.send(content, nil)
+}
+
+func (r *interactionReplier) replyWithReader(content string, filename string, reader io.Reader) {
+	r.send(content, []*discordgo.File{{Name: filename, Reader: reader}})
+}
+
+func (r *interactionReplier) replyEditable(content string) func(content string) error {
+	messageID, ok := r.send(content, nil)
+	if !ok {
+		return nil
+	}
+	return func(content string) error {
+		if messageID == "" {
+			return r.m.interactionResponseEdit(r.interaction, &discordgo.WebhookEdit{Content: &content})
+		}
+		return r.m.followupMessageEdit(r.interaction, messageID, &discordgo.WebhookEdit{Content: &content})
+	}
+}
+
+// send answers the interaction, fills in its deferred answer, or sends a followup message if it's already been answered,
+// returning the followup's message ID ("" for the answer itself) and whether it was sent at all.
+func (r *interactionReplier) send(content string, files []*discordgo.File) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch r.state {
+	case interactionUnanswered:
+		err := r.m.interactionRespond(r.interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Content: content, Files: files},
+		})
+		if err != nil {
+			return "", false
+		}
+		r.state = interactionAnswered
+		return "", true
+	case interactionDeferred:
+		err := r.m.interactionResponseEdit(r.interaction, &discordgo.WebhookEdit{Content: &content, Files: files})
+		if err != nil {
+			return "", false
+		}
+		r.state = interactionAnswered
+		return "", true
+	}
+	sent := r.m.followupMessageCreate(r.interaction, &discordgo.WebhookParams{Content: content, Files: files})
+	if sent == nil {
+		return "", false
+	}
+	return sent.ID, true
 }
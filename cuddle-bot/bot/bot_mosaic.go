@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"io"
+	"log/slog"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/cmmonosmith/cuddle-bot/asciify"
+)
+
+const (
+	// mosaics are sent as regular messages, and phones only fit about this many emoji across before wrapping
+	mosaicMaxWidth, mosaicMaxHeight = 14, 18
+	// custom emoji images are tiny, anything bigger isn't worth downloading
+	emojiMaxDownload = 1 << 20
+
+	argCustom = "custom"
+)
+
+// mosaicArgs are the arguments to the mosaic command.
+var mosaicArgs = slices.Concat(imageArgs(mosaicMaxWidth, mosaicMaxHeight), []Arg{
+	{Name: argCustom, Description: "use this server's custom emoji too", Type: ArgBoolean},
+	fitArg,
+	cropArg,
+}, toneArgs)
+
+// mosaic converts an image to colored emoji, and replies with them directly in a message sized to fit Discord's limit.
+func (b *bot) mosaic(r *request, body io.Reader, opts asciify.Options) {
+	m, err := asciify.Decode(body, opts)
+	if err != nil {
+		b.asciifyFailed(r, err)
+		return
+	}
+	palette := asciify.StandardEmoji(opts.Background)
+	if r.boolArg(argCustom) {
+		palette = append(palette, b.guildEmoji(r.guildID)...)
+	}
+
+	reply := ":white_check_mark: mosaicked: :jigsaw:\n"
+	opts.MaxChars = discordMessageLimit - utf8.RuneCountInString(reply)
+	text, err := asciify.Mosaic(m, palette, opts)
+	if err != nil {
+		b.asciifyFailed(r, err)
+		return
+	}
+	r.reply(reply + text)
+}
+
+// guildEmoji returns the palette of a server's custom emoji, as far as their colors have been precomputed.
+func (b *bot) guildEmoji(guildID string) []asciify.Emoji {
+	b.emojiMu.RLock()
+	defer b.emojiMu.RUnlock()
+	return b.emoji[guildID]
+}
+
+// precomputeEmoji downloads and averages the colors of a server's custom emoji, so mosaics can use them without waiting on
+// hundreds of downloads. Animated emoji and emoji restricted to certain roles are left out, since they can't be drawn or
+// used everywhere. Startup and Discord's events can start several of these for the same server at once, and since event
+// handlers run concurrently, not even in the order their events arrived, each one takes the server's latest emoji from the
+// session state, which is updated in order, and only keeps its palette if no other one has started since.
+func (b *bot) precomputeEmoji(guildID string, emojis []*discordgo.Emoji) {
+	b.emojiMu.Lock()
+	b.emojiGen[guildID]++
+	generation := b.emojiGen[guildID]
+	if guild, err := b.s.State.Guild(guildID); err == nil {
+		b.s.State.RLock()
+		emojis = slices.Clone(guild.Emojis)
+		b.s.State.RUnlock()
+	}
+	b.emojiMu.Unlock()
+
+	// Discord's default theme is dark, the same as asciify assumes
+	opts := asciify.Options{Background: asciify.BackgroundDark, MaxPixels: asciifyMaxPixels}
+	palette := make([]asciify.Emoji, 0, len(emojis))
+	for _, e := range emojis {
+		if e.Animated || !e.Available || len(e.Roles) > 0 {
+			continue
+		}
+		body, err := b.download(discordgo.EndpointEmoji(e.ID), emojiMaxDownload)
+		if err != nil {
+			slog.Error("failed to download emoji", slog.String("emoji", e.Name), slog.Any("error", err))
+			continue
+		}
+		m, err := asciify.Decode(body, opts)
+		body.Close()
+		if err != nil {
+			slog.Error("failed to decode emoji", slog.String("emoji", e.Name), slog.Any("error", err))
+			continue
+		}
+		palette = append(palette, asciify.NewEmoji(e.MessageFormat(), m, opts))
+	}
+
+	b.emojiMu.Lock()
+	defer b.emojiMu.Unlock()
+	if b.emojiGen[guildID] != generation {
+		slog.Info("discarded outdated emoji", slog.String("guild", guildID))
+		return
+	}
+	b.emoji[guildID] = palette
+	slog.Info("precomputed emoji", slog.String("guild", guildID), slog.Int("count", len(palette)))
+}
@@ -5,12 +5,16 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"slices"
+	"sync/atomic"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 var (
-	instance *bot
+	// instance is set once the session is open, but Discord starts sending events as soon as it opens, so handlers load it
+	// atomically and drop events that arrive before it's set
+	instance atomic.Pointer[bot]
 )
 
 // Run creates and starts the Discord session. Once running, it waits for an interrupt signal, after which it will exit.
@@ -24,6 +28,8 @@ func Run(token string) int {
 
 	session.AddHandler(newMessage)
 	session.AddHandler(interactionCreate)
+	session.AddHandler(guildCreate)
+	session.AddHandler(guildEmojisUpdate)
 
 	err = session.Open()
 	if err != nil {
@@ -36,8 +42,19 @@ func Run(token string) int {
 		slog.Error("no valid user in session")
 		return 1
 	}
-	instance = newBot(session, newMessenger(session))
-	instance.registerCommands()
+	b := newBot(session, newMessenger(session))
+	instance.Store(b)
+	b.registerCommands()
+
+	// servers that showed up before the bot was ready to handle them are already in the session state, though the ones that
+	// are still unavailable are just stubs, and get their emoji when their GUILD_CREATE arrives
+	session.State.RLock()
+	for _, guild := range session.State.Guilds {
+		if !guild.Unavailable {
+			go b.precomputeEmoji(guild.ID, slices.Clone(guild.Emojis))
+		}
+	}
+	session.State.RUnlock()
 
 	slog.Info("bot is running")
 	waitForInterrupt()
@@ -54,10 +71,30 @@ func waitForInterrupt() {
 
 // newMessage is the handler for Discord's MESSAGE_CREATE event, which simply calls the bot's implementation.
 func newMessage(session *discordgo.Session, message *discordgo.MessageCreate) {
-	instance.newMessage(message)
+	if b := instance.Load(); b != nil {
+		b.newMessage(message)
+	}
 }
 
 // interactionCreate is the handler for Discord's INTERACTION_CREATE event, which simply calls the bot's implementation.
 func interactionCreate(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
-	instance.interactionCreate(interaction)
+	if b := instance.Load(); b != nil {
+		b.interactionCreate(interaction)
+	}
+}
+
+// guildCreate is the handler for Discord's GUILD_CREATE event, which precomputes the server's custom emoji colors in the
+// background.
+func guildCreate(session *discordgo.Session, guild *discordgo.GuildCreate) {
+	if b := instance.Load(); b != nil {
+		go b.precomputeEmoji(guild.ID, guild.Emojis)
+	}
+}
+
+// guildEmojisUpdate is the handler for Discord's GUILD_EMOJIS_UPDATE event, which recomputes the server's custom emoji colors
+// in the background.
+func guildEmojisUpdate(session *discordgo.Session, update *discordgo.GuildEmojisUpdate) {
+	if b := instance.Load(); b != nil {
+		go b.precomputeEmoji(update.GuildID, update.Emojis)
+	}
 }
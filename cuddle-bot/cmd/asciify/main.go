@@ -0,0 +1,137 @@
+// Command asciify converts an image to text with the asciify package, the same way the bot does, for use in shell scripts or
+// to preview changes without running the bot. The image is read from a file, or stdin if there's no file or it's "-", and
+// the output is written to stdout unless -o is given.
+//
+// usage: asciify [-width 80] [-height 40] [-mode ramp] [-ramp chars|-preset name] [-invert] [-dither none] [-format txt]
+// [-o file] [flags] [file]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+
+	"github.com/cmmonosmith/cuddle-bot/asciify"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run does all the work of main, returning the exit code instead of exiting, so deferred calls still run when it fails.
+func run() int {
+	width := flag.Int("width", 80, "maximum width of the output in characters")
+	height := flag.Int("height", 40, "maximum height of the output in characters")
+	mode := flag.String("mode", "ramp", "renderer: ramp, braille, halfblock, edges, or glyph")
+	ramp := flag.String("ramp", "", "characters to draw from darkest to lightest on a light background, defaults to the default preset")
+	preset := flag.String("preset", "", "named ramp: default, discord, discord16, gomono, gomono16, or blocks")
+	invert := flag.Bool("invert", false, "flip the ramp, drawing a negative of the image")
+	dither := flag.String("dither", "none", "dithering: none, fs, atkinson, or bayer")
+	resample := flag.String("resample", "box", "resampling: nearest, box, bilinear, or lanczos")
+	color := flag.String("color", "none", "color escape sequences: none, discord, 256, or truecolor")
+	background := flag.String("background", "dark", "background the output will be displayed on: light or dark")
+	fit := flag.String("fit", "fit", "sizing: fit, fill, or stretch")
+	format := flag.String("format", "txt", "output format: txt, html, svg, or png")
+	output := flag.String("o", "", "file to write the output to, defaults to stdout")
+	flag.Parse()
+
+	opts, err := options(*mode, *ramp, *preset, *dither, *resample, *color, *background, *fit)
+	if err != nil {
+		return fail("bad flags", err)
+	}
+	opts.MaxWidth, opts.MaxHeight = *width, *height
+	opts.Invert = *invert
+	opts.Workers = runtime.GOMAXPROCS(0)
+	write, ok := writers[*format]
+	if !ok {
+		return fail("bad flags", fmt.Errorf("unknown output format (%s)", *format))
+	}
+
+	var in io.Reader = os.Stdin
+	if name := flag.Arg(0); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fail("failed to open image", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	m, err := asciify.Decode(in, opts)
+	if err != nil {
+		return fail("failed to decode image", err)
+	}
+	g, err := asciify.Render(m, opts)
+	if err != nil {
+		return fail("failed to asciify image", err)
+	}
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if *output != "" {
+		if file, err = os.Create(*output); err != nil {
+			return fail("failed to create output", err)
+		}
+		out = file
+	}
+	err = write(out, g, opts)
+	if file != nil {
+		// closing flushes the file, so it can fail even after every write succeeded
+		err = errors.Join(err, file.Close())
+	}
+	if err != nil {
+		return fail("failed to write output", err)
+	}
+	return 0
+}
+
+// options builds asciify options from the flags that name one of the package's modes.
+func options(mode, ramp, preset, dither, resample, color, background, fit string) (asciify.Options, error) {
+	var opts asciify.Options
+	var err error
+	if opts.Mode, err = asciify.ParseMode(mode); err != nil {
+		return opts, err
+	}
+	opts.Ramp = ramp
+	if preset != "" {
+		if ramp != "" {
+			return opts, fmt.Errorf("-ramp and -preset can't be used together")
+		}
+		if opts.Ramp, err = asciify.ParsePreset(preset); err != nil {
+			return opts, err
+		}
+	}
+	if opts.Dither, err = asciify.ParseDither(dither); err != nil {
+		return opts, err
+	}
+	if opts.Resample, err = asciify.ParseResample(resample); err != nil {
+		return opts, err
+	}
+	if opts.Color, err = asciify.ParseColorMode(color); err != nil {
+		return opts, err
+	}
+	if opts.Background, err = asciify.ParseBackground(background); err != nil {
+		return opts, err
+	}
+	if opts.Fit, err = asciify.ParseFit(fit); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// writers encode the rendered grid for each output format.
+var writers = map[string]func(io.Writer, *asciify.Grid, asciify.Options) error{
+	"txt":  asciify.WriteText,
+	"html": asciify.WriteHTML,
+	"svg":  asciify.WriteSVG,
+	"png":  asciify.WritePNG,
+}
+
+// fail logs why the command failed, returning the exit code for it.
+func fail(msg string, err error) int {
+	slog.Error(msg, slog.Any("error", err))
+	return 1
+}
@@ -0,0 +1,69 @@
+// Command calibrate measures how much ink each character covers in a font, and prints a character ramp for the asciify
+// package ordered from darkest to lightest.
+//
+// usage: calibrate [-font file.ttf] [-size 48] [-chars chars] [-n count] [-v]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+
+	"github.com/cmmonosmith/cuddle-bot/asciify"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run does all the work of main, returning the exit code instead of exiting, so the font face is closed even when it fails.
+func run() int {
+	fontFile := flag.String("font", "", "TTF or OTF font file to calibrate for, defaults to Go Mono")
+	size := flag.Float64("size", 48, "size in pixels to draw characters at, bigger is more precise")
+	chars := flag.String("chars", asciify.CalibrationChars, "candidate characters for the ramp")
+	n := flag.Int("n", 0, "number of characters in the ramp, or 0 to use every candidate")
+	verbose := flag.Bool("v", false, "print each character's coverage too")
+	flag.Parse()
+
+	data := gomono.TTF
+	if *fontFile != "" {
+		var err error
+		if data, err = os.ReadFile(*fontFile); err != nil {
+			slog.Error("failed to read font", slog.Any("error", err))
+			return 1
+		}
+	}
+	f, err := opentype.Parse(data)
+	if err != nil {
+		slog.Error("failed to parse font", slog.Any("error", err))
+		return 1
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: *size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		slog.Error("failed to create font face", slog.Any("error", err))
+		return 1
+	}
+	defer face.Close()
+
+	ramp, err := asciify.Calibrate(face, *chars, *n)
+	if err != nil {
+		slog.Error("failed to calibrate", slog.Any("error", err))
+		return 1
+	}
+	if *verbose {
+		coverages := map[rune]float64{}
+		for _, c := range asciify.MeasureCoverage(face, *chars) {
+			coverages[c.Rune] = c.Coverage
+		}
+		for _, r := range ramp {
+			fmt.Printf("%q\t%.4f\n", r, coverages[r])
+		}
+	}
+	fmt.Printf("%q\n", ramp)
+	return 0
+}
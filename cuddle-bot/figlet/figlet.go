@@ -0,0 +1,196 @@
+// Package figlet draws big banner text with FIGlet fonts (.flf files), the same way the figlet command does, and embeds a few
+// fonts of its own.
+package figlet
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Horizontal layout bits, as stored in a font header's full_layout field. The rules only apply when smushing.
+const (
+	ruleEqual = 1 << iota
+	ruleUnderscore
+	ruleHierarchy
+	rulePair
+	ruleBigX
+	ruleHardblank
+	layoutKerning
+	layoutSmushing
+
+	ruleMask = ruleEqual | ruleUnderscore | ruleHierarchy | rulePair | ruleBigX | ruleHardblank
+)
+
+// deutsch are the characters every FIGlet font is supposed to define right after printable ASCII, although plenty of fonts
+// leave them out.
+var deutsch = []rune{'Ä', 'Ö', 'Ü', 'ä', 'ö', 'ü', 'ß'}
+
+// Font is a parsed FIGlet font.
+type Font struct {
+	// Height is the number of rows every character is drawn with.
+	Height int
+	// Baseline is the number of rows from the top of a character to the baseline of the text, not counting descenders.
+	Baseline int
+	// Comment is the font's description, usually including its author and license.
+	Comment string
+
+	hardblank rune
+	layout    int
+	glyphs    map[rune][][]rune
+}
+
+// Parse reads a font in the FIGlet 2 (.flf) format. The required printable ASCII and German characters are read in order,
+// then any code tagged characters after them. Only horizontal layout is supported; text is always laid out left to right,
+// and rows of text are stacked without vertical smushing.
+func Parse(r io.Reader) (*Font, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	next := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		return strings.TrimSuffix(scanner.Text(), "\r"), true
+	}
+
+	header, ok := next()
+	if !ok {
+		return nil, errors.New("font is empty")
+	}
+	f, comments, err := parseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	var comment []string
+	for i := 0; i < comments; i++ {
+		line, ok := next()
+		if !ok {
+			return nil, errors.New("font ends in its comment")
+		}
+		comment = append(comment, line)
+	}
+	f.Comment = strings.Join(comment, "\n")
+
+	// the required characters have no tags, so they can only be identified by their order
+	required := make([]rune, 0, 95+len(deutsch))
+	for c := ' '; c <= '~'; c++ {
+		required = append(required, c)
+	}
+	required = append(required, deutsch...)
+	for _, c := range required {
+		glyph, err := f.readGlyph(next)
+		if errors.Is(err, io.EOF) {
+			return f, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("bad character %q: %w", c, err)
+		}
+		f.glyphs[c] = glyph
+	}
+
+	// the rest start with a tag line giving their code point, optionally followed by a name
+	for {
+		tag, ok := next()
+		if !ok {
+			return f, nil
+		}
+		fields := strings.Fields(tag)
+		if len(fields) == 0 {
+			continue
+		}
+		code, err := strconv.ParseInt(fields[0], 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad character code (%s)", fields[0])
+		}
+		glyph, err := f.readGlyph(next)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("bad character %d: %w", code, err)
+		}
+		// negative codes are reserved for characters that can't be typed
+		if code >= 0 {
+			f.glyphs[rune(code)] = glyph
+		}
+	}
+}
+
+// parseHeader parses the first line of a font, e.g. "flf2a$ 6 5 16 15 13 0 24463", returning the font with its settings and
+// the number of comment lines that follow.
+func parseHeader(header string) (*Font, int, error) {
+	fields := strings.Fields(header)
+	if len(fields) < 6 || !strings.HasPrefix(fields[0], "flf2a") || len(fields[0]) == len("flf2a") {
+		return nil, 0, errors.New("not a FIGlet font")
+	}
+	hardblank, _ := utf8.DecodeRuneInString(fields[0][len("flf2a"):])
+	n := make([]int, len(fields)-1)
+	for i, field := range fields[1:] {
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, 0, fmt.Errorf("bad font header (%s)", header)
+		}
+		n[i] = v
+	}
+	height, baseline, oldLayout, comments := n[0], n[1], n[3], n[4]
+	if height < 1 || comments < 0 {
+		return nil, 0, fmt.Errorf("bad font header (%s)", header)
+	}
+
+	// fonts from before full_layout existed pack the same information into old_layout, with -1 meaning full width and 0
+	// meaning kerning
+	layout := 0
+	switch {
+	case len(n) >= 7:
+		layout = n[6] & (ruleMask | layoutKerning | layoutSmushing)
+	case oldLayout == 0:
+		layout = layoutKerning
+	case oldLayout > 0:
+		layout = oldLayout&ruleMask | layoutSmushing
+	}
+
+	return &Font{
+		Height:    height,
+		Baseline:  baseline,
+		hardblank: hardblank,
+		layout:    layout,
+		glyphs:    map[rune][][]rune{},
+	}, comments, nil
+}
+
+// readGlyph reads the rows of one character, stripping the endmarks from the end of each. Rows are padded to the same width,
+// in case a font is sloppy about it.
+func (f *Font) readGlyph(next func() (string, bool)) ([][]rune, error) {
+	glyph := make([][]rune, f.Height)
+	width := 0
+	for i := range glyph {
+		line, ok := next()
+		if !ok {
+			if i == 0 {
+				return nil, io.EOF
+			}
+			return nil, io.ErrUnexpectedEOF
+		}
+		// the endmark is whatever the last character is, and there are usually two of them on the last row
+		row := []rune(strings.TrimRight(line, " \t"))
+		if n := len(row); n > 0 {
+			endmark := row[n-1]
+			for n > 0 && row[n-1] == endmark {
+				n--
+			}
+			row = row[:n]
+		}
+		glyph[i] = row
+		width = max(width, len(row))
+	}
+	for i, row := range glyph {
+		for len(row) < width {
+			row = append(row, ' ')
+		}
+		glyph[i] = row
+	}
+	return glyph, nil
+}
@@ -0,0 +1,32 @@
+package figlet
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"slices"
+)
+
+// DefaultFont is the name of the embedded font to use when there's no reason to pick another.
+const DefaultFont = "standard"
+
+// Fonts lists the names of the embedded fonts:
+//   - standard is drawn with lines and slashes in the style of FIGlet's standard font, and smushes them together
+//   - block is drawn with half block characters from a 7x13 bitmap font
+//   - braille is drawn with braille patterns from the same bitmap font, packing each character into 3 rows
+var Fonts = []string{"standard", "block", "braille"}
+
+//go:embed fonts/*.flf
+var fontFiles embed.FS
+
+// Load parses one of the embedded Fonts by name, e.g. "standard".
+func Load(name string) (*Font, error) {
+	if !slices.Contains(Fonts, name) {
+		return nil, fmt.Errorf("unknown font (%s)", name)
+	}
+	data, err := fontFiles.ReadFile("fonts/" + name + ".flf")
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(data))
+}
@@ -0,0 +1,109 @@
+package figlet
+
+import (
+	"strings"
+)
+
+// Render draws text in the font, returning the rows of each line of text joined by newlines, with trailing spaces trimmed.
+// Newlines in the text start a new line, and if width is positive, lines are wrapped between words (or within words, if
+// one word alone is too wide) so each is at most width columns wide. Characters the font doesn't have are skipped.
+func (f *Font) Render(text string, width int) string {
+	var sb strings.Builder
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line []rune
+		for _, word := range strings.Fields(paragraph) {
+			// add whole words while they fit, otherwise start the word on a new line
+			if len(line) > 0 {
+				candidate := append(append(line[:len(line):len(line)], ' '), []rune(word)...)
+				if width <= 0 || f.width(candidate) <= width {
+					line = candidate
+					continue
+				}
+				f.write(&sb, line)
+				line = nil
+			}
+			// break up words that are too wide by themselves wherever they run out of room
+			for _, c := range word {
+				candidate := append(line[:len(line):len(line)], c)
+				if width > 0 && len(line) > 0 && f.width(candidate) > width {
+					f.write(&sb, line)
+					candidate = []rune{c}
+				}
+				line = candidate
+			}
+		}
+		if len(line) > 0 {
+			f.write(&sb, line)
+		}
+	}
+	return sb.String()
+}
+
+// width is how many columns wide a line of text is once it's drawn.
+func (f *Font) width(text []rune) int {
+	return len(f.draw(text)[0])
+}
+
+// write draws a line of text and adds its rows to the output, swapping hardblanks for spaces now that smushing is over.
+func (f *Font) write(sb *strings.Builder, text []rune) {
+	for _, row := range f.draw(text) {
+		line := strings.ReplaceAll(string(row), string(f.hardblank), " ")
+		sb.WriteString(strings.TrimRight(line, " "))
+		sb.WriteString("\n")
+	}
+}
+
+// draw lays out a line of text, moving each character left into the ones before it as far as the font's layout allows.
+func (f *Font) draw(text []rune) [][]rune {
+	rows := make([][]rune, f.Height)
+	prevWidth := 0
+	for _, c := range text {
+		glyph, ok := f.glyphs[c]
+		if !ok {
+			continue
+		}
+		width := len(glyph[0])
+		amount := f.overlap(rows, glyph, prevWidth)
+		for i, row := range rows {
+			// overlapping columns past the start of the line can only be blank, so they're simply dropped
+			for k := 0; k < amount; k++ {
+				if col := len(row) - amount + k; col >= 0 {
+					if c, ok := f.smush(row[col], glyph[i][k], prevWidth, width); ok {
+						row[col] = c
+					}
+				}
+			}
+			rows[i] = append(row, glyph[i][amount:]...)
+		}
+		prevWidth = width
+	}
+	return rows
+}
+
+// overlap is how many columns a character can move left into the line, which is how far its closest visible character on
+// any row can go before touching the line's visible characters, plus one more column if they can smush together.
+func (f *Font) overlap(rows [][]rune, glyph [][]rune, prevWidth int) int {
+	if f.layout&(layoutKerning|layoutSmushing) == 0 {
+		return 0
+	}
+	width := len(glyph[0])
+	amount := width
+	for i, row := range rows {
+		last := len(row) - 1
+		for last >= 0 && row[last] == ' ' {
+			last--
+		}
+		first := 0
+		for first < width && glyph[i][first] == ' ' {
+			first++
+		}
+		n := first + len(row) - 1 - last
+		if last >= 0 && first < width {
+			if _, ok := f.smush(row[last], glyph[i][first], prevWidth, width); ok {
+				n++
+			}
+		}
+		amount = min(amount, n)
+	}
+	return amount
+}
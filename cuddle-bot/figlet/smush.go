@@ -0,0 +1,87 @@
+package figlet
+
+import (
+	"strings"
+)
+
+// hierarchy lists the classes of characters for the hierarchy rule, from weakest to strongest.
+var hierarchy = []string{"|", "/\\", "[]", "{}", "()", "<>"}
+
+// smush combines the character at the end of the line with the one at the start of the next character, when they're allowed
+// to occupy the same column. Blanks give way to anything, and otherwise it depends on the font's layout: universal smushing
+// keeps the later character, while controlled smushing only combines characters according to the font's rules.
+func (f *Font) smush(left, right rune, leftWidth, rightWidth int) (rune, bool) {
+	switch {
+	case left == ' ':
+		return right, true
+	case right == ' ':
+		return left, true
+	case leftWidth < 2 || rightWidth < 2:
+		// a character that narrow would be swallowed whole
+		return 0, false
+	case f.layout&layoutSmushing == 0:
+		return 0, false
+	}
+
+	rules := f.layout & ruleMask
+	if rules == 0 {
+		switch {
+		case left == f.hardblank:
+			return right, true
+		case right == f.hardblank:
+			return left, true
+		}
+		return right, true
+	}
+
+	if left == f.hardblank || right == f.hardblank {
+		return left, rules&ruleHardblank != 0 && left == right
+	}
+	if rules&ruleEqual != 0 && left == right {
+		return left, true
+	}
+	if rules&ruleUnderscore != 0 {
+		const borders = "|/\\[]{}()<>"
+		if left == '_' && strings.ContainsRune(borders, right) {
+			return right, true
+		} else if right == '_' && strings.ContainsRune(borders, left) {
+			return left, true
+		}
+	}
+	if rules&ruleHierarchy != 0 {
+		l, r := hierarchyClass(left), hierarchyClass(right)
+		if l >= 0 && r >= 0 && l != r {
+			if l > r {
+				return left, true
+			}
+			return right, true
+		}
+	}
+	if rules&rulePair != 0 {
+		switch string([]rune{left, right}) {
+		case "[]", "][", "{}", "}{", "()", ")(":
+			return '|', true
+		}
+	}
+	if rules&ruleBigX != 0 {
+		switch string([]rune{left, right}) {
+		case "/\\":
+			return '|', true
+		case "\\/":
+			return 'Y', true
+		case "><":
+			return 'X', true
+		}
+	}
+	return 0, false
+}
+
+// hierarchyClass returns the strength of a character's class for the hierarchy rule, or -1 if it isn't in one.
+func hierarchyClass(c rune) int {
+	for i, class := range hierarchy {
+		if strings.ContainsRune(class, c) {
+			return i
+		}
+	}
+	return -1
+}
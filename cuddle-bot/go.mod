@@ -4,11 +4,12 @@ go 1.23.2
 
 require (
 	github.com/bwmarrin/discordgo v0.28.1
-	github.com/google/uuid v1.6.0
+	golang.org/x/image v0.25.0
 )
 
 require (
 	github.com/gorilla/websocket v1.4.2 // indirect
 	golang.org/x/crypto v0.0.0-20210421170649-83a5a9bb288b // indirect
 	golang.org/x/sys v0.0.0-20201119102817-f84b799fce68 // indirect
+	golang.org/x/text v0.23.0 // indirect
 )